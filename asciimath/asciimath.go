@@ -0,0 +1,349 @@
+// Package asciimath converts AsciiMath formulas to LaTeX, see http://asciimath.org/#syntax.
+// Only the common subset of the syntax is supported: symbols, brackets, fractions, sub- and superscripts,
+// unary and binary functions.
+package asciimath
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type kind int
+
+const (
+	constant kind = iota
+	unary
+	binary
+	leftBracket
+	rightBracket
+	infix // "/", "_" and "^"
+	text  // quoted text "..."
+)
+
+type symbol struct {
+	kind kind
+	tex  string
+}
+
+var symbols = map[string]symbol{
+	//operation symbols
+	"+": {constant, "+"}, "-": {constant, "-"}, "*": {constant, `\cdot`}, "**": {constant, `\ast`},
+	"***": {constant, `\star`}, "//": {constant, "/"}, `\\`: {constant, `\backslash`}, "xx": {constant, `\times`},
+	"-:": {constant, `\div`}, "|><": {constant, `\ltimes`}, "><|": {constant, `\rtimes`}, "|><|": {constant, `\bowtie`},
+	"@": {constant, `\circ`}, "o+": {constant, `\oplus`}, "ox": {constant, `\otimes`}, "o.": {constant, `\odot`},
+	"sum": {constant, `\sum`}, "prod": {constant, `\prod`}, "^^": {constant, `\wedge`}, "^^^": {constant, `\bigwedge`},
+	"vv": {constant, `\vee`}, "vvv": {constant, `\bigvee`}, "nn": {constant, `\cap`}, "nnn": {constant, `\bigcap`},
+	"uu": {constant, `\cup`}, "uuu": {constant, `\bigcup`},
+	//relation symbols
+	"=": {constant, "="}, "!=": {constant, `\ne`}, "<": {constant, "<"}, ">": {constant, ">"},
+	"<=": {constant, `\le`}, ">=": {constant, `\ge`}, "-<": {constant, `\prec`}, ">-": {constant, `\succ`},
+	"in": {constant, `\in`}, "!in": {constant, `\notin`}, "sub": {constant, `\subset`}, "sup": {constant, `\supset`},
+	"sube": {constant, `\subseteq`}, "supe": {constant, `\supseteq`}, "-=": {constant, `\equiv`},
+	"~=": {constant, `\cong`}, "~~": {constant, `\approx`}, "prop": {constant, `\propto`},
+	//logical symbols
+	"and": {constant, `\text{ and }`}, "or": {constant, `\text{ or }`}, "not": {constant, `\neg`},
+	"=>": {constant, `\Rightarrow`}, "if": {constant, `\text{ if }`}, "<=>": {constant, `\Leftrightarrow`},
+	"AA": {constant, `\forall`}, "EE": {constant, `\exists`}, "_|_": {constant, `\bot`}, "TT": {constant, `\top`},
+	"|--": {constant, `\vdash`}, "|==": {constant, `\models`},
+	//miscellaneous symbols
+	"int": {constant, `\int`}, "oint": {constant, `\oint`}, "del": {constant, `\partial`}, "grad": {constant, `\nabla`},
+	"+-": {constant, `\pm`}, "O/": {constant, `\emptyset`}, "oo": {constant, `\infty`}, "aleph": {constant, `\aleph`},
+	":.": {constant, `\therefore`}, ":'": {constant, `\because`}, "...": {constant, `\ldots`}, "cdots": {constant, `\cdots`},
+	"vdots": {constant, `\vdots`}, "ddots": {constant, `\ddots`}, `\ `: {constant, `\ `}, "quad": {constant, `\quad`},
+	"qquad": {constant, `\qquad`}, "/_": {constant, `\angle`}, "frown": {constant, `\frown`}, "/_\\": {constant, `\triangle`},
+	"diamond": {constant, `\diamond`}, "square": {constant, `\square`}, "|__": {constant, `\lfloor`},
+	"__|": {constant, `\rfloor`}, "|~": {constant, `\lceil`}, "~|": {constant, `\rceil`},
+	"CC": {constant, `\mathbb{C}`}, "NN": {constant, `\mathbb{N}`}, "QQ": {constant, `\mathbb{Q}`},
+	"RR": {constant, `\mathbb{R}`}, "ZZ": {constant, `\mathbb{Z}`},
+	//arrows
+	"uarr": {constant, `\uparrow`}, "darr": {constant, `\downarrow`}, "rarr": {constant, `\rightarrow`},
+	"->": {constant, `\to`}, ">->": {constant, `\rightarrowtail`}, "->>": {constant, `\twoheadrightarrow`},
+	"|->": {constant, `\mapsto`}, "larr": {constant, `\leftarrow`}, "harr": {constant, `\leftrightarrow`},
+	"rArr": {constant, `\Rightarrow`}, "lArr": {constant, `\Leftarrow`}, "hArr": {constant, `\Leftrightarrow`},
+	//standard functions
+	"sin": {constant, `\sin`}, "cos": {constant, `\cos`}, "tan": {constant, `\tan`}, "sec": {constant, `\sec`},
+	"csc": {constant, `\csc`}, "cot": {constant, `\cot`}, "arcsin": {constant, `\arcsin`},
+	"arccos": {constant, `\arccos`}, "arctan": {constant, `\arctan`}, "sinh": {constant, `\sinh`},
+	"cosh": {constant, `\cosh`}, "tanh": {constant, `\tanh`}, "coth": {constant, `\coth`}, "exp": {constant, `\exp`},
+	"log": {constant, `\log`}, "ln": {constant, `\ln`}, "det": {constant, `\det`}, "dim": {constant, `\dim`},
+	"mod": {constant, `\bmod`}, "gcd": {constant, `\gcd`}, "lcm": {constant, `\operatorname{lcm}`},
+	"lub": {constant, `\operatorname{lub}`}, "glb": {constant, `\operatorname{glb}`}, "min": {constant, `\min`},
+	"max": {constant, `\max`}, "lim": {constant, `\lim`}, "Lim": {constant, `\operatorname{Lim}`},
+	//unary functions, the argument is enclosed in braces
+	"sqrt": {unary, `\sqrt`}, "text": {unary, `\text`}, "abs": {unary, `|`}, "floor": {unary, `\lfloor`},
+	"ceil": {unary, `\lceil`}, "norm": {unary, `\|`}, "hat": {unary, `\hat`}, "bar": {unary, `\overline`},
+	"overline": {unary, `\overline`}, "vec": {unary, `\vec`}, "dot": {unary, `\dot`}, "ddot": {unary, `\ddot`},
+	"ul": {unary, `\underline`}, "underline": {unary, `\underline`}, "tilde": {unary, `\tilde`},
+	"ubrace": {unary, `\underbrace`}, "obrace": {unary, `\overbrace`}, "bb": {unary, `\mathbf`},
+	"bbb": {unary, `\mathbb`}, "cc": {unary, `\mathcal`}, "tt": {unary, `\mathtt`}, "sf": {unary, `\mathsf`},
+	"fr": {unary, `\mathfrak`},
+	//binary functions
+	"frac": {binary, `\frac`}, "root": {binary, `\sqrt`}, "stackrel": {binary, `\stackrel`},
+	"overset": {binary, `\overset`}, "underset": {binary, `\underset`},
+	//brackets
+	"(": {leftBracket, "("}, ")": {rightBracket, ")"}, "[": {leftBracket, "["}, "]": {rightBracket, "]"},
+	"{": {leftBracket, `\{`}, "}": {rightBracket, `\}`}, "(:": {leftBracket, `\langle`}, ":)": {rightBracket, `\rangle`},
+	"<<": {leftBracket, `\langle`}, ">>": {rightBracket, `\rangle`}, "{:": {leftBracket, ""}, ":}": {rightBracket, ""},
+	//infix operators
+	"/": {infix, "/"}, "_": {infix, "_"}, "^": {infix, "^"},
+}
+
+var greek = []string{"alpha", "beta", "gamma", "Gamma", "delta", "Delta", "epsilon", "varepsilon", "zeta", "eta",
+	"theta", "Theta", "vartheta", "iota", "kappa", "lambda", "Lambda", "mu", "nu", "xi", "Xi", "pi", "Pi", "rho",
+	"sigma", "Sigma", "tau", "upsilon", "phi", "Phi", "varphi", "chi", "psi", "Psi", "omega", "Omega"}
+
+// symbol names sorted by length, the longest match wins
+var names []string
+
+func init() {
+	for _, g := range greek {
+		symbols[g] = symbol{constant, `\` + g}
+	}
+	for name := range symbols {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) == len(names[j]) {
+			return names[i] < names[j]
+		}
+		return len(names[i]) > len(names[j])
+	})
+}
+
+type token struct {
+	symbol
+	input string
+}
+
+var numberRE = regexp.MustCompile(`^\d+(?:\.\d+)?`)
+
+func tokenize(s string) []token {
+	var res []token
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeftFunc(s, unicode.IsSpace) {
+		if s[0] == '"' {
+			if end := strings.IndexByte(s[1:], '"'); end != -1 {
+				res = append(res, token{symbol{text, s[1 : end+1]}, s[:end+2]})
+				s = s[end+2:]
+				continue
+			}
+		}
+		if num := numberRE.FindString(s); num != "" {
+			res = append(res, token{symbol{constant, num}, num})
+			s = s[len(num):]
+			continue
+		}
+		found := false
+		for _, name := range names {
+			if strings.HasPrefix(s, name) {
+				res = append(res, token{symbols[name], name})
+				s = s[len(name):]
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		//variable or unknown character
+		r, n := utf8.DecodeRuneInString(s)
+		tex := string(r)
+		if strings.ContainsRune(`#$%&~`, r) {
+			tex = `\` + tex
+		}
+		res = append(res, token{symbol{constant, tex}, s[:n]})
+		s = s[n:]
+	}
+	return res
+}
+
+// node is a converted expression, inner is the bracketed expression without brackets
+type node struct {
+	tex   string
+	inner string
+}
+
+// arg returns the expression used as a function argument, brackets are dropped
+func (n node) arg() string {
+	if n.inner != "" {
+		return n.inner
+	}
+	return n.tex
+}
+
+type converter struct {
+	tokens []token
+	pos    int
+}
+
+func (c *converter) peek() *token {
+	if c.pos < len(c.tokens) {
+		return &c.tokens[c.pos]
+	}
+	return nil
+}
+
+func (c *converter) next() *token {
+	t := c.peek()
+	if t != nil {
+		c.pos++
+	}
+	return t
+}
+
+// ToLatex converts AsciiMath formula to LaTeX. Error is returned if the formula is malformed,
+// e.g. it has unbalanced brackets.
+func ToLatex(s string) (string, error) {
+	c := converter{tokens: tokenize(s)}
+	res, err := c.expr()
+	if err != nil {
+		return "", err
+	}
+	if t := c.peek(); t != nil {
+		return "", fmt.Errorf("unexpected %q", t.input)
+	}
+	return res, nil
+}
+
+// expr parses "I E" and "I/I E" sequences until the end or the closing bracket
+func (c *converter) expr() (string, error) {
+	var res string
+	for t := c.peek(); t != nil && t.kind != rightBracket; t = c.peek() {
+		n, err := c.intermediate()
+		if err != nil {
+			return "", err
+		}
+		if t := c.peek(); t != nil && t.input == "/" {
+			c.next()
+			d, err := c.intermediate()
+			if err != nil {
+				return "", err
+			}
+			n = node{tex: `\frac{` + n.arg() + "}{" + d.arg() + "}"}
+		}
+		res = concat(res, n.tex)
+	}
+	return res, nil
+}
+
+// intermediate parses "S", "S_S", "S^S" and "S_S^S"
+func (c *converter) intermediate() (node, error) {
+	n, err := c.simple()
+	if err != nil {
+		return node{}, err
+	}
+	for _, op := range []string{"_", "^"} {
+		if t := c.peek(); t != nil && t.input == op {
+			c.next()
+			s, err := c.simple()
+			if err != nil {
+				return node{}, err
+			}
+			n = node{tex: n.tex + op + "{" + s.arg() + "}"}
+		}
+	}
+	return n, nil
+}
+
+// simple parses a symbol, a bracketed expression, a unary or binary function with its arguments
+func (c *converter) simple() (node, error) {
+	t := c.next()
+	if t == nil {
+		return node{}, fmt.Errorf("unexpected end of formula")
+	}
+	switch t.kind {
+	case constant:
+		return node{tex: t.tex}, nil
+	case text:
+		return node{tex: `\text{` + t.tex + "}"}, nil
+	case leftBracket:
+		inner, err := c.expr()
+		if err != nil {
+			return node{}, err
+		}
+		r := c.next()
+		if r == nil {
+			return node{}, fmt.Errorf("no closing bracket for %q", t.input)
+		}
+		left, right := t.tex, r.tex
+		if t.tex != "" && r.tex != "" && t.tex != "(" && t.tex != "[" {
+			left, right = `\left`+left, `\right`+right
+		}
+		return node{tex: concat(concat(left, inner), right), inner: inner}, nil
+	case unary:
+		if t.input == "text" {
+			return c.rawText()
+		}
+		arg, err := c.simple()
+		if err != nil {
+			return node{}, err
+		}
+		switch t.input {
+		case "abs", "norm":
+			return node{tex: `\left` + t.tex + arg.arg() + `\right` + t.tex}, nil
+		case "floor":
+			return node{tex: `\left\lfloor ` + arg.arg() + `\right\rfloor`}, nil
+		case "ceil":
+			return node{tex: `\left\lceil ` + arg.arg() + `\right\rceil`}, nil
+		}
+		return node{tex: t.tex + "{" + arg.arg() + "}"}, nil
+	case binary:
+		a, err := c.simple()
+		if err != nil {
+			return node{}, err
+		}
+		b, err := c.simple()
+		if err != nil {
+			return node{}, err
+		}
+		if t.input == "root" {
+			return node{tex: `\sqrt[` + a.arg() + "]{" + b.arg() + "}"}, nil
+		}
+		return node{tex: t.tex + "{" + a.arg() + "}{" + b.arg() + "}"}, nil
+	}
+	return node{}, fmt.Errorf("unexpected %q", t.input)
+}
+
+// rawText reads "text(...)" argument as is
+func (c *converter) rawText() (node, error) {
+	t := c.next()
+	if t == nil || t.kind != leftBracket {
+		return node{}, fmt.Errorf("no text in brackets")
+	}
+	var b strings.Builder
+	for depth := 1; ; {
+		t = c.next()
+		if t == nil {
+			return node{}, fmt.Errorf("no closing bracket for text")
+		}
+		if t.kind == leftBracket {
+			depth++
+		} else if t.kind == rightBracket {
+			if depth--; depth == 0 {
+				break
+			}
+		}
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(t.input)
+	}
+	return node{tex: `\text{` + b.String() + "}"}, nil
+}
+
+var commandEndRE = regexp.MustCompile(`\\[a-zA-Z]+$`)
+
+// concat joins LaTeX fragments, a space is inserted if the command name would run into the letters after it
+func concat(a, b string) string {
+	if b == "" {
+		return a
+	}
+	r, _ := utf8.DecodeRuneInString(b)
+	if unicode.IsLetter(r) && commandEndRE.MatchString(a) {
+		return a + " " + b
+	}
+	return a + b
+}
@@ -0,0 +1,42 @@
+package asciimath
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestToLatex(t *testing.T) {
+	cases := []struct {
+		input string
+		exp   string
+	}{
+		{"x^2", "x^{2}"},
+		{"sqrt(x^2+1)", `\sqrt{x^{2}+1}`},
+		{"a/b", `\frac{a}{b}`},
+		{"(a+b)/(c-d)", `\frac{a+b}{c-d}`},
+		{"sum_(i=1)^n i^3", `\sum_{i=1}^{n}i^{3}`},
+		{"alpha xx beta", `\alpha\times\beta`},
+		{"sin x <= 1", `\sin x\le1`},
+		{"root(3)(x)", `\sqrt[3]{x}`},
+		{"frac a 2", `\frac{a}{2}`},
+		{`"speed" = d/t`, `\text{speed}=\frac{d}{t}`},
+		{"text(if) x > 0", `\text{if}x>0`},
+		{"abs(x)", `\left|x\right|`},
+		{"{x in RR}", `\left\{x\in\mathbb{R}\right\}`},
+		{"lim_(x->oo) 1/x = 0", `\lim_{x\to\infty}\frac{1}{x}=0`},
+		{"E = mc^2", `E=mc^{2}`},
+	}
+	for _, c := range cases {
+		res, err := ToLatex(c.input)
+		if assert.NoError(t, err, c.input) {
+			assert.Equal(t, c.exp, res, c.input)
+		}
+	}
+}
+
+func TestToLatex_Errors(t *testing.T) {
+	for _, s := range []string{"(a+b", "sqrt", "a/", "a)"} {
+		_, err := ToLatex(s)
+		assert.Error(t, err, s)
+	}
+}
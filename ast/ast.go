@@ -5,6 +5,7 @@ import (
 	"asciidoc2md/utils"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +14,43 @@ type Block interface {
 	String() string
 }
 
+// Pos is the source range of the node, lines and columns start from 1
+type Pos struct {
+	File string //source file, included documents have their own file names
+	Line uint
+	Col uint
+	EndLine uint //line of the last char
+	EndCol uint //column of the last char
+}
+
+// Positioned is implemented by all the nodes through the embedded Pos
+type Positioned interface {
+	Position() *Pos
+}
+
+func (p *Pos) Position() *Pos {
+	return p
+}
+
+// Location returns the location of the block, see Pos.Location
+func Location(b Block) string {
+	if p, ok := b.(Positioned); ok && !utils.IsNil(b) {
+		return p.Position().Location()
+	}
+	return ""
+}
+
+// Location returns "file:line:col" or "line:col" if the file is unknown, empty string for unknown position
+func (p Pos) Location() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
 type (
 	WalkerFunc func (Block, *Document) bool
 	Walker interface {
@@ -21,6 +59,7 @@ type (
 )
 
 type ContainerBlock struct {
+	Pos
 	Blocks []Block
 }
 
@@ -32,6 +71,10 @@ func (b *ContainerBlock) Append(blok ...Block) {
 	b.Blocks = append(b.Blocks, blok...)
 }
 
+func (b *ContainerBlock) Prepend(blok Block) {
+	b.Blocks = append([]Block{blok}, b.Blocks...)
+}
+
 func (b *ContainerBlock) Walk(f WalkerFunc, root *Document) bool {
 	for _, blok := range b.Blocks {
 		if !f(blok, root) {
@@ -74,6 +117,41 @@ func (b *ContainerBlock) StringWithIndent(indent string) string {
 type Document struct {
 	ContainerBlock
 	Name string //adoc file name, empty for root doc
+	Attributes map[string]string //document attributes ":name: value"
+	Header *DocumentHeader //document title, authors and revision, nil if there is no document header
+}
+
+type Author struct {
+	Name string
+	Email string
+}
+
+type Revision struct {
+	Number string
+	Date string
+	Remark string
+}
+
+// DocumentHeader is the "= Title" line followed by the optional author line, revision line and attribute entries.
+type DocumentHeader struct {
+	Title string
+	Subtitle string //title part after the last colon: "= Title: Subtitle"
+	Authors []Author
+	Revision Revision
+	Attributes map[string]string //attributes defined in the header
+}
+
+func (h *DocumentHeader) String() string {
+	authors := make([]string, 0, len(h.Authors))
+	for _, a := range h.Authors {
+		if a.Email != "" {
+			authors = append(authors, fmt.Sprintf("%s <%s>", a.Name, a.Email))
+		} else {
+			authors = append(authors, a.Name)
+		}
+	}
+	return fmt.Sprintf("document header: %s; subtitle: %s; authors: %s; revision: %s, %s: %s",
+		h.Title, h.Subtitle, strings.Join(authors, "; "), h.Revision.Number, h.Revision.Date, h.Revision.Remark)
 }
 
 func (d *Document) StringWithIndent(indent string) string {
@@ -89,6 +167,7 @@ var _ Walker = (*Document)(nil)
 
 type Paragraph struct {
 	ContainerBlock
+	NoReplacements bool //"subs=-replacements" option, "(C)", "--", "=>", etc. are left as is
 }
 
 var _ Walker = (*Paragraph)(nil)
@@ -166,11 +245,23 @@ func (ex *ExampleBlock) StringWithIndent(indent string) string {
 
 
 type Header struct {
+	Pos
 	Level int
 	Text string
 	Id string
 	Float bool //not a header, just formatted like a header text
 	Options string
+	RefText string //text used as a caption of the links to this header
+	Style string //section style: "appendix", "glossary", "preface" and so on
+	Number string //section number: "1.2." or "Appendix A.", empty if the section isn't numbered
+}
+
+// Title returns the header text prefixed with the section number
+func (h *Header) Title() string {
+	if h.Number == "" {
+		return h.Text
+	}
+	return h.Number + " " + h.Text
 }
 
 func (h *Header) StringWithIndent(indent string) string {
@@ -182,7 +273,15 @@ func (h *Header) StringWithIndent(indent string) string {
 	if h.Options != "" {
 		opts = ", " + h.Options
 	}
-	return fmt.Sprintf("\n%sheader: %v, %v%v%v", indent, h.Level, h.Text, id, opts)
+	var refText string
+	if h.RefText != "" {
+		refText = ", reftext: " + h.RefText
+	}
+	var number string
+	if h.Number != "" {
+		number = ", number: " + h.Number
+	}
+	return fmt.Sprintf("\n%sheader: %v, %v%v%v%v%v", indent, h.Level, h.Text, id, opts, refText, number)
 }
 
 func (h *Header) String() string {
@@ -190,6 +289,7 @@ func (h *Header) String() string {
 }
 
 type BlockTitle struct {
+	Pos
 	Title string
 }
 
@@ -203,6 +303,7 @@ func (t *BlockTitle) String() string {
 
 
 type List struct {
+	Pos
 	Items []*ContainerBlock
 	Parent *List
 	Marker string
@@ -210,6 +311,33 @@ type List struct {
 	Numbered bool
 	Definition bool //definitions list
 	Callouts bool //callouts list
+	Checklist bool //some items start with a checkbox "[x]" or "[ ]"
+	Style string //numbering style: "arabic", "loweralpha", "upperalpha", "lowerroman", "upperroman"
+	Start int //number of the first item, 0 if not specified
+	Reversed bool //"%reversed" option
+	Bibliography bool //items are bibliography entries starting with "[[[ref]]]"
+}
+
+var listStyles = []string{"arabic", "decimal", "loweralpha", "upperalpha", "lowerroman", "upperroman", "lowergreek"}
+
+// SetOptions applies "[loweralpha, start=5%reversed]"-like block options.
+func (l *List) SetOptions(options string) {
+	if options == "" {
+		return
+	}
+	attrs := ParseAttributes(options)
+	for _, s := range listStyles {
+		if attrs.Style == s {
+			l.Style = s
+		}
+	}
+	if start, err := strconv.Atoi(attrs.Get("start")); err == nil {
+		l.Start = start
+	}
+	l.Reversed = attrs.Has("reversed")
+	if attrs.Style == "bibliography" {
+		l.Bibliography = true
+	}
 }
 
 func (l *List) Walk(f WalkerFunc, root *Document) bool {
@@ -241,6 +369,18 @@ func (l *List) StringWithIndent(indent string) string {
 	str := strings.Builder{}
 	//ind2 := strings.Repeat("  ", l.Level)
 	str.WriteString(fmt.Sprintf("\n%slist begin: (%v/%v/%v)", indent, l.Level, l.Numbered, l.Marker))
+	if l.Style != "" {
+		str.WriteString(" " + l.Style)
+	}
+	if l.Start != 0 {
+		str.WriteString(fmt.Sprintf(" start=%v", l.Start))
+	}
+	if l.Reversed {
+		str.WriteString(" reversed")
+	}
+	if l.Checklist {
+		str.WriteString(" checklist")
+	}
 
 	for i, item := range l.Items {
 		if item != nil {
@@ -275,7 +415,26 @@ func (l *List) LastItem() *ContainerBlock {
 	return l.Items[len(l.Items) - 1]
 }
 
+// CheckBox is a checklist item state "[x]" or "[ ]", it is the first inline block of the item's first paragraph.
+type CheckBox struct {
+	Pos
+	Checked bool
+}
+
+func (c *CheckBox) StringWithIndent(indent string) string {
+	mark := " "
+	if c.Checked {
+		mark = "x"
+	}
+	return fmt.Sprintf("\n%scheckbox: [%s]", indent, mark)
+}
+
+func (c *CheckBox) String() string {
+	return c.StringWithIndent("")
+}
+
 type SyntaxBlock struct {
+	Pos
 	Options string
 	Literal string
 	Lang string
@@ -312,6 +471,7 @@ func (sb *SyntaxBlock) String() string {
 }
 
 type Image struct {
+	Pos
 	Path string
 	Options string
 }
@@ -324,7 +484,84 @@ func (i *Image) String() string {
 	return i.StringWithIndent("")
 }
 
+// MediaOptions are common attributes of audio and video macros
+type MediaOptions struct {
+	Start string //start time in seconds
+	End string //end time in seconds
+	Autoplay bool
+	Loop bool
+	NoControls bool
+}
+
+func (m *MediaOptions) setOptions(attrs *Attributes) {
+	m.Start = attrs.Get("start")
+	m.End = attrs.Get("end")
+	m.Autoplay = attrs.Has("autoplay")
+	m.Loop = attrs.Has("loop")
+	m.NoControls = attrs.Has("nocontrols")
+}
+
+// Video is a "video::file.mp4[width=640, start=10, options=autoplay]" or "video::id[youtube]" block macro
+type Video struct {
+	Pos
+	MediaOptions
+	Target string //file name or video id of the provider
+	Provider string //"youtube", "vimeo" or empty for the video file
+	Width string
+	Height string
+	Poster string
+	Muted bool
+}
+
+func NewVideo(target string, options string) *Video {
+	attrs := ParseAttributes(options)
+	v := &Video{Target: target, Width: attrs.Get("width"), Height: attrs.Get("height"), Poster: attrs.Get("poster")}
+	if attrs.Style == "youtube" || attrs.Style == "vimeo" {
+		v.Provider = attrs.Style
+		//"video::id[youtube, 640, 360]"
+		if v.Width == "" {
+			v.Width = attrs.Pos(1)
+		}
+		if v.Height == "" {
+			v.Height = attrs.Pos(2)
+		}
+	}
+	v.setOptions(attrs)
+	v.Muted = attrs.Has("muted")
+	return v
+}
+
+func (v *Video) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%svideo: %s, %s", indent, v.Target, v.Provider)
+}
+
+func (v *Video) String() string {
+	return v.StringWithIndent("")
+}
+
+// Audio is a "audio::file.mp3[start=10, options=loop]" block macro
+type Audio struct {
+	Pos
+	MediaOptions
+	Target string
+}
+
+func NewAudio(target string, options string) *Audio {
+	a := &Audio{Target: target}
+	a.setOptions(ParseAttributes(options))
+	return a
+}
+
+func (a *Audio) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%saudio: %s", indent, a.Target)
+}
+
+func (a *Audio) String() string {
+	return a.StringWithIndent("")
+}
+
 type InlineImage struct {
+	Pos
 	Path string
 	Options string
 }
@@ -339,6 +576,7 @@ func (i *InlineImage) String() string {
 
 
 type Text struct {
+	Pos
 	Text string
 }
 
@@ -351,7 +589,77 @@ func (t *Text) String() string {
 	return t.StringWithIndent("")
 }
 
+// LineBreak is a hard line break: "text +" line ending or a line inside "[%hardbreaks]" paragraph.
+type LineBreak struct {
+	Pos
+}
+
+func (b *LineBreak) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%sline break", indent)
+}
+
+func (b *LineBreak) String() string {
+	return b.StringWithIndent("")
+}
+
+// Comment is a comment line "// text" or a comment block delimited by "////".
+type Comment struct {
+	Pos
+	Text string
+	Block bool
+}
+
+func (c *Comment) StringWithIndent(indent string) string {
+	if c.Block {
+		return fmt.Sprintf("\n%scomment block: %s", indent, c.Text)
+	}
+	return fmt.Sprintf("\n%scomment: %s", indent, c.Text)
+}
+
+func (c *Comment) String() string {
+	return c.StringWithIndent("")
+}
+
+// Toc is a table of contents placed by "toc::[]" macro or generated for the split file.
+// Entries are filled by the splitter after all the output file names are known.
+type Toc struct {
+	Pos
+	Title string
+	Entries []*TocEntry
+}
+
+type TocEntry struct {
+	Pos
+	Level int
+	Text string
+	Url string
+	Entries []*TocEntry
+}
+
+func (t *Toc) StringWithIndent(indent string) string {
+	s := strings.Builder{}
+	s.WriteString(fmt.Sprintf("\n%stoc: %s", indent, t.Title))
+	for _, e := range t.Entries {
+		s.WriteString(e.StringWithIndent(indent + "  "))
+	}
+	return s.String()
+}
+
+func (t *Toc) String() string {
+	return t.StringWithIndent("")
+}
+
+func (e *TocEntry) StringWithIndent(indent string) string {
+	s := strings.Builder{}
+	s.WriteString(fmt.Sprintf("\n%s%v, %s (%s)", indent, e.Level, e.Text, e.Url))
+	for _, child := range e.Entries {
+		s.WriteString(child.StringWithIndent(indent + "  "))
+	}
+	return s.String()
+}
+
 type HorLine struct {
+	Pos
 }
 
 func (i *HorLine) StringWithIndent(indent string) string {
@@ -363,6 +671,7 @@ func (i *HorLine) String() string {
 }
 
 type Admonition struct {
+	Pos
 	Kind string
 	Content *ContainerBlock
 }
@@ -386,10 +695,36 @@ func (a *Admonition) String() string {
 }
 
 type Table struct {
+	Pos
 	Header bool
 	Options string
 	Columns int
 	Cells   []*ContainerBlock
+	Spans   map[int]CellSpan //cell index -> span of the cell, only spanned cells are listed
+}
+
+// CellSpan is the number of the columns and the rows the table cell occupies: "2.3+|" spans 2 columns and 3 rows
+type CellSpan struct {
+	Cols int
+	Rows int
+}
+
+var cellSpanRE = regexp.MustCompile(`^(\d*)(?:\.(\d+))?\+`)
+
+// ParseCellSpan parses the span of the "2+|", ".3+|", "2.3+a|" cell specifier
+func ParseCellSpan(spec string) CellSpan {
+	span := CellSpan{1, 1}
+	matches := cellSpanRE.FindStringSubmatch(spec)
+	if matches == nil {
+		return span
+	}
+	if n, err := strconv.Atoi(matches[1]); err == nil && n > 0 {
+		span.Cols = n
+	}
+	if n, err := strconv.Atoi(matches[2]); err == nil && n > 0 {
+		span.Rows = n
+	}
+	return span
 }
 
 func (t *Table) Walk(f WalkerFunc, root *Document) bool {
@@ -412,6 +747,99 @@ func (t *Table) AddColumn(c *ContainerBlock) {
 	t.Cells = append(t.Cells, c)
 }
 
+// SetSpan sets the span of the i-th cell
+func (t *Table) SetSpan(i int, span CellSpan) {
+	if span.Cols <= 1 && span.Rows <= 1 {
+		return
+	}
+	if t.Spans == nil {
+		t.Spans = make(map[int]CellSpan)
+	}
+	t.Spans[i] = span
+}
+
+// Span returns the span of the i-th cell
+func (t *Table) Span(i int) CellSpan {
+	if span, ok := t.Spans[i]; ok {
+		return span
+	}
+	return CellSpan{1, 1}
+}
+
+// Grid lays out the cells on the rows x columns grid, every grid position contains the index of the cell
+// starting at the position, positions covered by the spanned cells contain -1.
+func (t *Table) Grid() [][]int {
+	if t.Columns == 0 {
+		return nil
+	}
+	var grid [][]int
+	row, col := 0, 0
+	for i := range t.Cells {
+		for {
+			for len(grid) <= row {
+				grid = append(grid, newGridRow(t.Columns))
+			}
+			if grid[row][col] == emptyPos {
+				break
+			}
+			col++
+			if col == t.Columns {
+				row++
+				col = 0
+			}
+		}
+		span := t.Span(i)
+		for r := row; r < row+span.Rows; r++ {
+			for len(grid) <= r {
+				grid = append(grid, newGridRow(t.Columns))
+			}
+			for c := col; c < col+span.Cols && c < t.Columns; c++ {
+				grid[r][c] = -1
+			}
+		}
+		grid[row][col] = i
+		col += span.Cols
+		if col >= t.Columns {
+			row++
+			col = 0
+		}
+	}
+	for r := range grid {
+		//positions left after the last cell
+		for c := range grid[r] {
+			if grid[r][c] == emptyPos {
+				grid[r][c] = -1
+			}
+		}
+	}
+	return grid
+}
+
+const emptyPos = -2 //grid position which isn't occupied yet
+
+func newGridRow(columns int) []int {
+	row := make([]int, columns)
+	for i := range row {
+		row[i] = emptyPos
+	}
+	return row
+}
+
+// Expanded returns a copy of the table without spans, the positions covered by the spanned cells are empty cells
+func (t *Table) Expanded() *Table {
+	res := &Table{Header: t.Header, Options: t.Options, Columns: t.Columns}
+	for _, row := range t.Grid() {
+		for _, i := range row {
+			if i == -1 {
+				res.AddColumn(&ContainerBlock{})
+			} else {
+				res.AddColumn(t.Cells[i])
+			}
+		}
+	}
+	return res
+}
+
 func (t *Table) StringWithIndent(indent string) string {
 	str := strings.Builder{}
 	//ind2 := strings.Repeat("  ", l.Level)
@@ -423,8 +851,11 @@ func (t *Table) StringWithIndent(indent string) string {
 		str.WriteString(" (not-so-simple)")
 	}
 
-	for _, cell := range t.Cells {
-		if cell != nil {
+	for i, cell := range t.Cells {
+		if span, ok := t.Spans[i]; ok && cell != nil {
+			str.WriteString(fmt.Sprintf("\n%scell (span %vx%v):", indent, span.Cols, span.Rows))
+			str.WriteString(cell.StringWithIndent(indent + "  "))
+		} else if cell != nil {
 			str.WriteString(fmt.Sprintf("\n%scell:", indent))
 			str.WriteString(cell.StringWithIndent(indent + "  "))
 			//str.WriteString("\n")
@@ -478,26 +909,225 @@ func (t *Table) IsDefList() bool {
 	return true
 }
 
-type Bookmark struct {
+// diagram types supported by Asciidoctor Diagram extension and mkdocs plugins
+var diagramTypes = []string{"plantuml", "mermaid", "graphviz", "ditaa"}
+
+// DiagramBlock is a "[plantuml, target=name, format=svg]" block, the target is the name of the generated image
+type DiagramBlock struct {
+	Pos
+	Type string
+	Target string
+	Format string
+	Attributes *Attributes
+	Literal string
+}
+
+// NewDiagramBlock returns nil if the block options have no diagram style.
+func NewDiagramBlock(options string, literal string) *DiagramBlock {
+	attrs := ParseAttributes(options)
+	for _, t := range diagramTypes {
+		if attrs.Style == t {
+			d := &DiagramBlock{Type: t, Attributes: attrs, Literal: literal}
+			//"[plantuml, name, svg]" positional form
+			d.Target = attrs.Pos(1)
+			d.Format = attrs.Pos(2)
+			if v := attrs.Get("target"); v != "" {
+				d.Target = v
+			}
+			if v := attrs.Get("format"); v != "" {
+				d.Format = v
+			}
+			return d
+		}
+	}
+	return nil
+}
+
+func (d *DiagramBlock) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%sdiagram: %s, %s, %s, %q", indent, d.Type, d.Target, d.Format, utils.ShortenString(d.Literal, 30, 30))
+}
+
+func (d *DiagramBlock) String() string {
+	return d.StringWithIndent("")
+}
+
+// PassBlock is a "++++" delimited passthrough block, its content is written as is
+type PassBlock struct {
+	Pos
 	Literal string
 }
 
+func (pb *PassBlock) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%spass block: %q", indent, utils.ShortenString(pb.Literal, 30, 30))
+}
+
+func (pb *PassBlock) String() string {
+	return pb.StringWithIndent("")
+}
+
+// Unknown is the source region skipped by the parser after an error, the text is written as is
+type Unknown struct {
+	Pos
+	Text string //raw source lines
+	Error string //parse error message
+}
+
+func (u *Unknown) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%sunknown: %q", indent, utils.ShortenString(u.Text, 30, 30))
+}
+
+func (u *Unknown) String() string {
+	return u.StringWithIndent("")
+}
+
+const (
+	LatexMath = "latexmath"
+	AsciiMath = "asciimath"
+)
+
+// Stem is a "[stem]", "[latexmath]" or "[asciimath]" passthrough block with a formula
+type Stem struct {
+	Pos
+	Notation string //LatexMath or AsciiMath
+	Text string
+}
+
+func (s *Stem) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%sstem: %s, %s", indent, s.Notation, s.Text)
+}
+
+func (s *Stem) String() string {
+	return s.StringWithIndent("")
+}
+
+// InlineStem is an inline formula "stem:[...]", "latexmath:[...]" or "asciimath:[...]"
+type InlineStem struct {
+	Pos
+	Notation string //LatexMath or AsciiMath
+	Text string
+}
+
+func (s *InlineStem) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%sinline stem: %s, %s", indent, s.Notation, s.Text)
+}
+
+func (s *InlineStem) String() string {
+	return s.StringWithIndent("")
+}
+
+// BibAnchor is a bibliography entry anchor "[[[ref,label]]]", the label is shown in the citations "<<ref>>"
+type BibAnchor struct {
+	Pos
+	Id    string
+	Label string
+}
+
+func (b *BibAnchor) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%sbib anchor: %s, %s", indent, b.Id, b.Label)
+}
+
+func (b *BibAnchor) String() string {
+	return b.StringWithIndent("")
+}
+
+type Bookmark struct {
+	Pos
+	Literal string //anchor id
+	RefText string //text used as a caption of the links to this anchor
+}
+
 func (b *Bookmark) StringWithIndent(indent string) string {
-	return fmt.Sprintf("\n%sbookmark: %s", indent, b.Literal)
+	var refText string
+	if b.RefText != "" {
+		refText = ", " + b.RefText
+	}
+	return fmt.Sprintf("\n%sbookmark: %s%s", indent, b.Literal, refText)
 }
 
 func (b *Bookmark) String() string {
 	return b.StringWithIndent("")
 }
 
+// IndexTerm is an index entry. Visible term "((term))" is rendered as text,
+// concealed term "(((primary, secondary, tertiary)))" is only added to the index.
+type IndexTerm struct {
+	Pos
+	Terms []string
+	Visible bool
+}
+
+func (t *IndexTerm) StringWithIndent(indent string) string {
+	kind := "concealed"
+	if t.Visible {
+		kind = "visible"
+	}
+	return fmt.Sprintf("\n%sindex term: (%s) %s", indent, kind, strings.Join(t.Terms, ", "))
+}
+
+func (t *IndexTerm) String() string {
+	return t.StringWithIndent("")
+}
+
+// Kbd is a keyboard shortcut "kbd:[Ctrl+S]"
+type Kbd struct {
+	Pos
+	Keys []string
+}
+
+func (k *Kbd) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%skbd: %s", indent, strings.Join(k.Keys, ", "))
+}
+
+func (k *Kbd) String() string {
+	return k.StringWithIndent("")
+}
+
+// Button is a UI button "btn:[Save]"
+type Button struct {
+	Pos
+	Text string
+}
+
+func (b *Button) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%sbutton: %s", indent, b.Text)
+}
+
+func (b *Button) String() string {
+	return b.StringWithIndent("")
+}
+
+// Menu is a menu selection "menu:File[Save As]", Items contain the top level menu and all the submenus
+type Menu struct {
+	Pos
+	Items []string
+}
+
+func (m *Menu) StringWithIndent(indent string) string {
+	return fmt.Sprintf("\n%smenu: %s", indent, strings.Join(m.Items, " > "))
+}
+
+func (m *Menu) String() string {
+	return m.StringWithIndent("")
+}
+
 type Link struct {
+	Pos
 	Url string
 	Text string
 	Internal bool
+	Window string //target window: "window=_blank" attribute or "^" at the end of the link text
+	Roles []string
 }
 
 func (l *Link) StringWithIndent(indent string) string {
-	return fmt.Sprintf("\n%slink: (%v,%s,%s)", indent, l.Internal, l.Text, l.Url)
+	var attrs string
+	if l.Window != "" {
+		attrs += ", window: " + l.Window
+	}
+	if len(l.Roles) > 0 {
+		attrs += ", roles: " + strings.Join(l.Roles, " ")
+	}
+	return fmt.Sprintf("\n%slink: (%v,%s,%s)%s", indent, l.Internal, l.Text, l.Url, attrs)
 }
 
 func (l *Link) String() string {
@@ -1,5 +1,13 @@
 package ast
 
+import (
+	"asciidoc2md/token"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
 var (
 	_ Block = (*Link)(nil)
 	_ Block = (*Header)(nil)
@@ -12,9 +20,123 @@ var (
 	_ Block = (*BlockTitle)(nil)
 	_ Block = (*SyntaxBlock)(nil)
 	_ Block = (*InlineImage)(nil)
+	_ Block = (*Video)(nil)
+	_ Block = (*Audio)(nil)
 	_ Block = (*Text)(nil)
 	_ Block = (*Admonition)(nil)
 	_ Block = (*Table)(nil)
 	_ Block = (*Bookmark)(nil)
+	_ Block = (*BibAnchor)(nil)
+	_ Block = (*PassBlock)(nil)
+	_ Block = (*DiagramBlock)(nil)
+	_ Block = (*Stem)(nil)
+	_ Block = (*InlineStem)(nil)
+	_ Block = (*CheckBox)(nil)
+	_ Block = (*LineBreak)(nil)
+	_ Block = (*IndexTerm)(nil)
+	_ Block = (*Kbd)(nil)
+	_ Block = (*Button)(nil)
+	_ Block = (*Menu)(nil)
+	_ Block = (*Comment)(nil)
+	_ Block = (*Toc)(nil)
+	_ Block = (*Unknown)(nil)
 )
 
+
+func TestParseAttributes(t *testing.T) {
+	attrs := ParseAttributes(`source#code.role1.role2%linenums, json, title="a, b", start=5`)
+	assert.Equal(t, "source", attrs.Style)
+	assert.Equal(t, "code", attrs.Id)
+	assert.Equal(t, []string{"role1", "role2"}, attrs.Roles)
+	assert.True(t, attrs.Has("linenums"))
+	assert.Equal(t, "json", attrs.Pos(1))
+	assert.Equal(t, "a, b", attrs.Get("title"))
+	assert.Equal(t, "5", attrs.Get("start"))
+	assert.Equal(t, []string{"%reversed"}, ParseAttributes("%reversed").Positional)
+	assert.True(t, ParseAttributes("%reversed").Has("reversed"))
+}
+
+func TestTableGrid(t *testing.T) {
+	table := &Table{Columns: 3}
+	for i := 0; i < 7; i++ {
+		table.AddColumn(&ContainerBlock{})
+	}
+	table.SetSpan(0, ParseCellSpan("2+|"))
+	table.SetSpan(2, ParseCellSpan(".2+a|"))
+	table.SetSpan(3, ParseCellSpan("|"))
+	assert.Equal(t, CellSpan{1, 1}, table.Span(3))
+	assert.Equal(t, [][]int{
+		{0, -1, 1},
+		{2, 3, 4},
+		{-1, 5, 6},
+	}, table.Grid())
+	assert.Len(t, table.Expanded().Cells, 9)
+	assert.Equal(t, CellSpan{2, 3}, ParseCellSpan("2.3+^.^s|"))
+}
+
+func TestJSON(t *testing.T) {
+	nested := &List{Marker: "**", Level: 2, Items: []*ContainerBlock{{Blocks: []Block{NewParagraphFromStr("nested")}}}}
+	list := &List{Marker: "*", Level: 1, Items: []*ContainerBlock{
+		{Blocks: []Block{NewParagraphFromStr("item 1"), nested}},
+		{Blocks: []Block{&Link{Url: "https://example.org", Text: "link", Window: "_blank", Roles: []string{"ext"}}}},
+	}}
+	nested.Parent = list
+	table := &Table{Columns: 2, Header: true}
+	table.AddColumn(&ContainerBlock{Blocks: []Block{NewParagraphFromStr("a")}})
+	table.AddColumn(&ContainerBlock{Blocks: []Block{NewParagraphFromStr("b")}})
+	table.SetSpan(0, CellSpan{2, 1})
+	ex := &ExampleBlock{Kind: "NOTE", Delim: &token.Token{Type: token.EX_BLOCK, Literal: "====", Line: 5}}
+	ex.Add(NewParagraphFromStr("example"))
+	doc := &Document{Name: "part.adoc", Attributes: map[string]string{"toc": ""},
+		Header: &DocumentHeader{Title: "Doc", Authors: []Author{{Name: "John", Email: "john@example.org"}}}}
+	doc.Append(&Header{Level: 2, Text: "Chapter", Id: "_chapter", Pos: Pos{File: "part.adoc", Line: 3, Col: 1, EndLine: 3, EndCol: 10}}, list, table, ex,
+		&Image{Path: "img.png", Options: "width=100"},
+		&Video{Target: "id", Provider: "youtube", MediaOptions: MediaOptions{Start: "10", Autoplay: true}})
+
+	data, err := ToJSON(doc)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"file": "part.adoc"`)
+	assert.Contains(t, string(data), `"endCol": 10`)
+	assert.Contains(t, string(data), `"type": "EX_BLOCK"`)
+	res, err := FromJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, doc.String(), res.String())
+	assert.Equal(t, doc, res)
+	resList := res.Blocks[1].(*List)
+	assert.Same(t, resList, resList.Items[0].Blocks[1].(*List).Parent)
+
+	cases := []struct {
+		json string
+		err  string
+	}{
+		{`{"version":2,"document":{"type":"document"}}`, "unsupported AST version"},
+		{`{"version":1,"document":{"type":"paragraph"}}`, "document expected"},
+		{`{"version":1,"document":{"type":"document","children":[{"type":"bad"}]}}`, "unknown node type"},
+		{`{"version":1,"document":{"type":"document","children":[{"type":"text","attrs":{"bad":1}}]}}`, "unknown attribute"},
+		{`{"version":1,"document":{"type":"document","children":[{"type":"text","children":[{"type":"text"}]}]}}`, "cannot have children"},
+	}
+	for _, c := range cases {
+		_, err := FromJSON([]byte(c.json))
+		if assert.Error(t, err, c.json) {
+			assert.Contains(t, err.Error(), c.err)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := ioutil.ReadFile("ast.schema.json")
+	assert.NoError(t, err)
+	var schema struct {
+		Definitions struct {
+			Node struct {
+				Properties struct {
+					Type struct {
+						Enum []string
+					}
+				}
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, NodeTypes(), schema.Definitions.Node.Properties.Type.Enum)
+}
@@ -0,0 +1,128 @@
+package ast
+
+import (
+	"strings"
+)
+
+// Attributes is a parsed block attribute list like "[style#id.role%option, positional, name=value]".
+type Attributes struct {
+	Style      string   // style from the first positional attribute: "source", "loweralpha", "NOTE", ...
+	Id         string   // "#id" shorthand or "id=..." named attribute
+	Roles      []string // ".role" shorthands or "role=..." named attribute
+	Options    []string // "%option" shorthands or "options=..."/"opts=..." named attribute
+	Positional []string // all positional attributes as is, the first one included
+	Named      map[string]string
+}
+
+// ParseAttributes parses block options literal (without enclosing brackets).
+func ParseAttributes(s string) *Attributes {
+	attrs := Attributes{Named: make(map[string]string)}
+	for i, part := range splitAttributes(s) {
+		eq := strings.Index(part, "=")
+		if eq > 0 && !strings.ContainsAny(part[:eq], " \"'") {
+			name := strings.TrimSpace(part[:eq])
+			value := unquote(strings.TrimSpace(part[eq+1:]))
+			attrs.Named[name] = value
+			switch name {
+			case "id":
+				attrs.Id = value
+			case "role":
+				attrs.Roles = append(attrs.Roles, strings.Fields(value)...)
+			case "options", "opts":
+				for _, opt := range strings.Split(value, ",") {
+					if opt = strings.TrimSpace(opt); opt != "" {
+						attrs.Options = append(attrs.Options, opt)
+					}
+				}
+			}
+			continue
+		}
+		part = unquote(part)
+		attrs.Positional = append(attrs.Positional, part)
+		if i == 0 {
+			attrs.parseShorthand(part)
+		}
+	}
+	return &attrs
+}
+
+// parseShorthand parses the first positional attribute: "style#id.role1.role2%opt1%opt2".
+func (a *Attributes) parseShorthand(s string) {
+	var buf strings.Builder
+	kind := byte(0) // shorthand kind of the current chunk, 0 is for style
+	emit := func() {
+		val := buf.String()
+		buf.Reset()
+		switch {
+		case kind == 0:
+			a.Style = val
+		case val == "":
+		case kind == '#':
+			a.Id = val
+		case kind == '.':
+			a.Roles = append(a.Roles, val)
+		case kind == '%':
+			a.Options = append(a.Options, val)
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '#', '.', '%':
+			emit()
+			kind = s[i]
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	emit()
+}
+
+// Has checks if the option is set either as "%option" or inside "options" attribute.
+func (a *Attributes) Has(opt string) bool {
+	for _, o := range a.Options {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns named attribute value or an empty string.
+func (a *Attributes) Get(name string) string {
+	return a.Named[name]
+}
+
+// Pos returns i-th positional attribute (zero based) or an empty string.
+func (a *Attributes) Pos(i int) string {
+	if i < len(a.Positional) {
+		return a.Positional[i]
+	}
+	return ""
+}
+
+// splitAttributes splits attribute list by commas, ignoring commas inside double quotes.
+func splitAttributes(s string) []string {
+	var parts []string
+	var quoted bool
+	beg := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(s[beg:i]))
+			beg = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[beg:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
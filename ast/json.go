@@ -0,0 +1,392 @@
+package ast
+
+import (
+	"asciidoc2md/token"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// JSONVersion is the version of the JSON AST format, it's increased on incompatible changes.
+// The format is described by "ast.schema.json" JSON Schema.
+const JSONVersion = 1
+
+type jsonDocument struct {
+	Version  int       `json:"version"`
+	Document *jsonNode `json:"document"`
+}
+
+// jsonNode is the serialized block: node type, source range, attributes and child blocks.
+// Attributes are the exported fields of the block in lowerCamelCase, fields with zero values are omitted.
+type jsonNode struct {
+	Type     string                     `json:"type"`
+	File     string                     `json:"file,omitempty"`
+	Line     uint                       `json:"line,omitempty"`
+	Col      uint                       `json:"col,omitempty"`
+	EndLine  uint                       `json:"endLine,omitempty"`
+	EndCol   uint                       `json:"endCol,omitempty"`
+	Attrs    map[string]json.RawMessage `json:"attrs,omitempty"`
+	Children []*jsonNode                `json:"children,omitempty"`
+}
+
+func (n *jsonNode) pos() Pos {
+	return Pos{File: n.File, Line: n.Line, Col: n.Col, EndLine: n.EndLine, EndCol: n.EndCol}
+}
+
+// nodeTypes is the registry of the block types, the names are a part of the JSON format
+var nodeTypes = map[string]reflect.Type{
+	"admonition":   reflect.TypeOf(Admonition{}),
+	"audio":        reflect.TypeOf(Audio{}),
+	"bib_anchor":   reflect.TypeOf(BibAnchor{}),
+	"block_title":  reflect.TypeOf(BlockTitle{}),
+	"bookmark":     reflect.TypeOf(Bookmark{}),
+	"button":       reflect.TypeOf(Button{}),
+	"checkbox":     reflect.TypeOf(CheckBox{}),
+	"comment":      reflect.TypeOf(Comment{}),
+	"container":    reflect.TypeOf(ContainerBlock{}),
+	"diagram":      reflect.TypeOf(DiagramBlock{}),
+	"document":     reflect.TypeOf(Document{}),
+	"example":      reflect.TypeOf(ExampleBlock{}),
+	"header":       reflect.TypeOf(Header{}),
+	"hor_line":     reflect.TypeOf(HorLine{}),
+	"image":        reflect.TypeOf(Image{}),
+	"index_term":   reflect.TypeOf(IndexTerm{}),
+	"inline_image": reflect.TypeOf(InlineImage{}),
+	"inline_stem":  reflect.TypeOf(InlineStem{}),
+	"kbd":          reflect.TypeOf(Kbd{}),
+	"line_break":   reflect.TypeOf(LineBreak{}),
+	"link":         reflect.TypeOf(Link{}),
+	"list":         reflect.TypeOf(List{}),
+	"list_block":   reflect.TypeOf(ListBlock{}),
+	"menu":         reflect.TypeOf(Menu{}),
+	"paragraph":    reflect.TypeOf(Paragraph{}),
+	"pass_block":   reflect.TypeOf(PassBlock{}),
+	"stem":         reflect.TypeOf(Stem{}),
+	"syntax_block": reflect.TypeOf(SyntaxBlock{}),
+	"table":        reflect.TypeOf(Table{}),
+	"text":         reflect.TypeOf(Text{}),
+	"toc":          reflect.TypeOf(Toc{}),
+	"toc_entry":    reflect.TypeOf(TocEntry{}),
+	"unknown":      reflect.TypeOf(Unknown{}),
+	"video":        reflect.TypeOf(Video{}),
+}
+
+var nodeNames = make(map[reflect.Type]string)
+
+func init() {
+	for name, t := range nodeTypes {
+		nodeNames[t] = name
+	}
+}
+
+// NodeTypes returns the sorted names of the JSON node types
+func NodeTypes() []string {
+	res := make([]string, 0, len(nodeTypes))
+	for name := range nodeTypes {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// skipped fields: container blocks are the node children, list parent is restored on loading
+var jsonSkipFields = map[string]bool{"ContainerBlock.Blocks": true, "List.Parent": true}
+
+var (
+	blockType     = reflect.TypeOf((*Block)(nil)).Elem()
+	containerType = reflect.TypeOf(ContainerBlock{})
+	posType       = reflect.TypeOf(Pos{})
+	tokenPtrType  = reflect.TypeOf(&token.Token{})
+)
+
+// ToJSON serializes the document into the versioned JSON
+func ToJSON(doc *Document) ([]byte, error) {
+	n, err := encodeNode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(&jsonDocument{JSONVersion, n}, "", "  ")
+}
+
+// FromJSON rebuilds the document from JSON produced by ToJSON or other tools
+func FromJSON(data []byte) (*Document, error) {
+	var jd jsonDocument
+	if err := json.Unmarshal(data, &jd); err != nil {
+		return nil, err
+	}
+	if jd.Version != JSONVersion {
+		return nil, fmt.Errorf("unsupported AST version: %v, expected %v", jd.Version, JSONVersion)
+	}
+	if jd.Document == nil {
+		return nil, fmt.Errorf("no document in AST")
+	}
+	b, err := decodeNode(jd.Document)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := b.(*Document)
+	if !ok {
+		return nil, fmt.Errorf("root node is %s, document expected", jd.Document.Type)
+	}
+	return doc, nil
+}
+
+// jsonName converts "RefText" field name to "refText" attribute name
+func jsonName(field string) string {
+	r := []rune(field)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// nodeFields returns the exported fields of the block struct including the fields of the embedded structs,
+// embedded ContainerBlock isn't included since its blocks are the node children, embedded Pos is the node range
+func nodeFields(t reflect.Type) []reflect.StructField {
+	var res []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || jsonSkipFields[t.Name()+"."+f.Name] {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if f.Type == containerType || f.Type == posType {
+				continue
+			}
+			for _, ef := range nodeFields(f.Type) {
+				ef.Index = append([]int{i}, ef.Index...)
+				res = append(res, ef)
+			}
+			continue
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+// children returns the child blocks of the node if the block struct embeds ContainerBlock
+func children(v reflect.Value) (*ContainerBlock, bool) {
+	if v.Type() == containerType {
+		return v.Addr().Interface().(*ContainerBlock), true
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Type().Field(i); f.Anonymous && f.Type == containerType {
+			return v.Field(i).Addr().Interface().(*ContainerBlock), true
+		}
+	}
+	return nil, false
+}
+
+func isBlockType(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Implements(blockType)
+}
+
+func encodeNode(b Block) (*jsonNode, error) {
+	v := reflect.ValueOf(b)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("cannot serialize block: %v", b)
+	}
+	v = v.Elem()
+	name, ok := nodeNames[v.Type()]
+	if !ok {
+		return nil, fmt.Errorf("cannot serialize block of unknown type: %v", v.Type())
+	}
+	pos := b.(Positioned).Position()
+	n := &jsonNode{Type: name, File: pos.File, Line: pos.Line, Col: pos.Col, EndLine: pos.EndLine, EndCol: pos.EndCol}
+	for _, f := range nodeFields(v.Type()) {
+		fv := v.FieldByIndex(f.Index)
+		if fv.IsZero() {
+			continue
+		}
+		val, err := encodeField(fv)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		if n.Attrs == nil {
+			n.Attrs = make(map[string]json.RawMessage)
+		}
+		n.Attrs[jsonName(f.Name)] = data
+	}
+	if cb, ok := children(v); ok {
+		for _, child := range cb.Blocks {
+			cn, err := encodeNode(child)
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, cn)
+		}
+	}
+	return n, nil
+}
+
+// encodeField converts the field value to the value marshaled by encoding/json
+func encodeField(v reflect.Value) (interface{}, error) {
+	switch {
+	case isBlockType(v.Type()):
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeNode(v.Interface().(Block))
+	case v.Kind() == reflect.Slice && isBlockType(v.Type().Elem()):
+		res := make([]*jsonNode, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			n, err := encodeField(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			node, _ := n.(*jsonNode)
+			res = append(res, node)
+		}
+		return res, nil
+	case v.Type() == tokenPtrType:
+		t := v.Interface().(*token.Token)
+		return map[string]interface{}{"type": t.Type.String(), "literal": t.Literal,
+			"line": t.Line, "col": t.Col, "endLine": t.EndLine, "endCol": t.EndCol}, nil
+	}
+	return encodeValue(v), nil
+}
+
+// encodeValue converts structs to maps with lowerCamelCase keys, other values are left as is
+func encodeValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return encodeValue(v.Elem())
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for _, f := range nodeFields(v.Type()) {
+			if fv := v.FieldByIndex(f.Index); !fv.IsZero() {
+				m[jsonName(f.Name)] = encodeValue(fv)
+			}
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		res := make([]interface{}, v.Len())
+		for i := range res {
+			res[i] = encodeValue(v.Index(i))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		res := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			res[fmt.Sprint(k.Interface())] = encodeValue(v.MapIndex(k))
+		}
+		return res
+	}
+	return v.Interface()
+}
+
+func decodeNode(n *jsonNode) (Block, error) {
+	if n == nil {
+		return nil, nil
+	}
+	t, ok := nodeTypes[n.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown node type: %q", n.Type)
+	}
+	ptr := reflect.New(t)
+	v := ptr.Elem()
+	fields := make(map[string]reflect.StructField)
+	for _, f := range nodeFields(t) {
+		fields[jsonName(f.Name)] = f
+	}
+	*ptr.Interface().(Positioned).Position() = n.pos()
+	for key, data := range n.Attrs {
+		f, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute %q of %s node", key, n.Type)
+		}
+		if err := decodeField(v.FieldByIndex(f.Index), data); err != nil {
+			return nil, fmt.Errorf("%s node, attribute %q: %v", n.Type, key, err)
+		}
+	}
+	if len(n.Children) > 0 {
+		cb, ok := children(v)
+		if !ok {
+			return nil, fmt.Errorf("%s node cannot have children", n.Type)
+		}
+		for _, child := range n.Children {
+			b, err := decodeNode(child)
+			if err != nil {
+				return nil, err
+			}
+			cb.Add(b)
+		}
+	}
+	if l, ok := ptr.Interface().(*List); ok {
+		for _, item := range l.Items {
+			for _, b := range item.Blocks {
+				if nested, ok := b.(*List); ok {
+					nested.Parent = l
+				}
+			}
+		}
+	}
+	return ptr.Interface().(Block), nil
+}
+
+func decodeField(v reflect.Value, data json.RawMessage) error {
+	switch {
+	case isBlockType(v.Type()):
+		var n *jsonNode
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		b, err := decodeNode(n)
+		if err != nil || b == nil {
+			return err
+		}
+		if !reflect.TypeOf(b).AssignableTo(v.Type()) {
+			return fmt.Errorf("%s node isn't allowed here", n.Type)
+		}
+		v.Set(reflect.ValueOf(b))
+	case v.Kind() == reflect.Slice && isBlockType(v.Type().Elem()):
+		var nodes []*jsonNode
+		if err := json.Unmarshal(data, &nodes); err != nil {
+			return err
+		}
+		res := reflect.MakeSlice(v.Type(), 0, len(nodes))
+		for _, n := range nodes {
+			b, err := decodeNode(n)
+			if err != nil {
+				return err
+			}
+			if b == nil || !reflect.TypeOf(b).AssignableTo(v.Type().Elem()) {
+				return fmt.Errorf("invalid node in the list: %v", n)
+			}
+			res = reflect.Append(res, reflect.ValueOf(b))
+		}
+		v.Set(res)
+	case v.Type() == tokenPtrType:
+		var t struct {
+			Type                       string
+			Literal                    string
+			Line, Col, EndLine, EndCol uint
+		}
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		tt := token.Lookup(strings.ToUpper(t.Type))
+		if tt == token.ILLEGAL && t.Type != "ILLEGAL" {
+			return fmt.Errorf("unknown token type: %q", t.Type)
+		}
+		v.Set(reflect.ValueOf(&token.Token{Type: tt, Literal: t.Literal, Line: t.Line, Col: t.Col, EndLine: t.EndLine, EndCol: t.EndCol}))
+	default:
+		//encoding/json matches lowerCamelCase keys to the field names
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	return nil
+}
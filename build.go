@@ -0,0 +1,199 @@
+package main
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/diag"
+	"asciidoc2md/idmap"
+	"asciidoc2md/settings"
+	"bytes"
+	"cdr.dev/slog"
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+)
+
+// guideBuild is the state of the guide conversion
+type guideBuild struct {
+	guide    *settings.Guide
+	conf     *settings.Config //project config with the guide input, nav file and artifacts folder
+	doc      *ast.Document
+	idMaps   *idmap.Registry //shared by all the guides
+	log      slog.Logger //writes to out, the guides are built in parallel
+	out      bytes.Buffer //log of the current step
+	diagOut  bytes.Buffer //diagnostics of the current step
+	files    int //number of the output files
+	updated  int //number of the changed output files
+	errors   int
+	warnings int
+	err      error //the guide failed
+}
+
+func buildProject() {
+	ctx := context.Background()
+	start := time.Now()
+	conf := initConfigCLI(cli.Config, &cli)
+	builds, err := projectBuilds(conf, cli.Build.Guides)
+	if err != nil {
+		log.Fatal(ctx, "cannot build project", slog.Error(err))
+	}
+	if err = os.MkdirAll(conf.Project.Artifacts(), 0777); err != nil {
+		log.Fatal(ctx, "cannot create artifacts folder", slog.Error(err))
+	}
+	idMaps := newIdMapRegistry(conf.Project.Artifacts())
+	for _, b := range builds {
+		b.idMaps = idMaps
+		b.log = makeLog(&b.out, cli.Debug)
+	}
+	//all the idmaps are written before converting, so the links between the guides are resolved
+	runJobs(builds, cli.Build.Jobs, (*guideBuild).genIdMap)
+	runJobs(builds, cli.Build.Jobs, (*guideBuild).convert)
+	if printSummary(os.Stdout, builds, time.Since(start)) > 0 {
+		os.Exit(1)
+	}
+}
+
+// projectBuilds returns the guides to build, all the project guides if names are empty
+func projectBuilds(conf *settings.Config, names []string) ([]*guideBuild, error) {
+	p := conf.Project
+	if p == nil || len(p.Guides) == 0 {
+		return nil, fmt.Errorf("no project guides in the config")
+	}
+	guides := p.Guides
+	if len(names) > 0 {
+		guides = nil
+		for _, name := range names {
+			g := p.Guide(name)
+			if g == nil {
+				return nil, fmt.Errorf("unknown guide: %s", name)
+			}
+			guides = append(guides, g)
+		}
+	}
+	var builds []*guideBuild
+	for _, g := range guides {
+		c := *conf
+		c.InputFile = p.SrcPath(g)
+		c.NavFile = p.NavPath(g)
+		c.ArtifactsDir = p.Artifacts()
+		builds = append(builds, &guideBuild{guide: g, conf: &c})
+	}
+	return builds, nil
+}
+
+// runJobs calls f for every not failed build on the pool of the jobs goroutines, the number of CPUs is used
+// if jobs isn't positive. The logs of the build are written when it and all the builds before it are done,
+// so the output doesn't depend on the timing.
+func runJobs(builds []*guideBuild, jobs int, f func(b *guideBuild) error) {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	done := make([]chan struct{}, len(builds))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	work := make(chan int)
+	for w := 0; w < jobs; w++ {
+		go func() {
+			for i := range work {
+				b := builds[i]
+				if b.err == nil {
+					b.err = catchPanic(func() error { return f(b) })
+				}
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range builds {
+			work <- i
+		}
+		close(work)
+	}()
+	for i, b := range builds {
+		<-done[i]
+		b.flush()
+	}
+}
+
+// flush writes the buffered log and diagnostics of the build
+func (b *guideBuild) flush() {
+	color.Output.Write(b.out.Bytes())
+	os.Stderr.Write(b.diagOut.Bytes())
+	b.out.Reset()
+	b.diagOut.Reset()
+}
+
+// catchPanic turns the panics of the splitter into errors, so the other guides are built
+func catchPanic(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return f()
+}
+
+func (b *guideBuild) genIdMap() error {
+	ctx := context.Background()
+	b.log.Info(ctx, "building idmap", slog.F("guide", b.guide.Name), slog.F("file", b.conf.InputFile))
+	var diags diag.Collector
+	doc, includes, err := parseFileDeps(b.conf.InputFile, b.log, &diags)
+	if err != nil {
+		return err
+	}
+	diags.Print(&b.diagOut)
+	b.errors, b.warnings = diags.Count(diag.Error), diags.Count(diag.Warning)
+	if b.errors > b.conf.MaxErrors {
+		return fmt.Errorf("too many parse errors: %d, max %d", b.errors, b.conf.MaxErrors)
+	}
+	b.doc = doc
+	gen := NewFileSplitter(doc, b.guide.Name, b.conf, "", b.guide.Level(), b.log)
+	gen.SetIdMaps(b.idMaps)
+	gen.SetSources(append([]string{b.conf.InputFile}, includes...))
+	//the idmap is put into the shared registry, the other guides don't load it
+	if err = gen.GenerateIdMap(); err != nil {
+		return err
+	}
+	b.updated += gen.updated
+	return nil
+}
+
+func (b *guideBuild) convert() error {
+	ctx := context.Background()
+	dest := b.conf.Project.DestPath(b.guide)
+	b.log.Info(ctx, "converting guide", slog.F("guide", b.guide.Name), slog.F("out", dest))
+	if err := os.MkdirAll(dest, 0777); err != nil {
+		return err
+	}
+	splitter := NewFileSplitter(b.doc, b.guide.Name, b.conf, dest, b.guide.Level(), b.log)
+	splitter.SetIdMaps(b.idMaps)
+	if err := splitter.RenderMarkdown(b.guide.Images()); err != nil {
+		return err
+	}
+	b.files = len(splitter.fileNames)
+	b.updated += splitter.updated
+	return nil
+}
+
+// printSummary writes the table of the built guides and returns the number of the failed ones
+func printSummary(w io.Writer, builds []*guideBuild, elapsed time.Duration) int {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "guide\tfiles\tupdated\terrors\twarnings\tstatus")
+	failed := 0
+	for _, b := range builds {
+		status := "ok"
+		if b.err != nil {
+			status = "failed: " + b.err.Error()
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", b.guide.Name, b.files, b.updated, b.errors, b.warnings, status)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d guide(s) built, %d failed in %v\n", len(builds)-failed, failed, elapsed.Round(time.Millisecond))
+	return failed
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"asciidoc2md/idmap"
+	"asciidoc2md/settings"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProjectBuilds(t *testing.T) {
+	conf := &settings.Config{MaxErrors: 3, Project: &settings.Project{
+		SrcDir:  "src",
+		DestDir: "docs",
+		Guides: []*settings.Guide{
+			{Name: "user", Src: "UserGuide.adoc", Dest: "user", NavFile: "user/.pages"},
+			{Name: "admin", Src: "AdminGuide.adoc", Dest: "admin"},
+		},
+	}}
+	builds, err := projectBuilds(conf, nil)
+	if assert.NoError(t, err) && assert.Len(t, builds, 2) {
+		assert.Equal(t, "src/UserGuide.adoc", builds[0].conf.InputFile)
+		assert.Equal(t, "docs/user/.pages", builds[0].conf.NavFile)
+		assert.Equal(t, settings.DefaultArtifactsDir, builds[0].conf.ArtifactsDir)
+		assert.Equal(t, 3, builds[0].conf.MaxErrors)
+		assert.Equal(t, "src/AdminGuide.adoc", builds[1].conf.InputFile)
+		assert.Equal(t, "", builds[1].conf.NavFile)
+	}
+	builds, err = projectBuilds(conf, []string{"admin"})
+	if assert.NoError(t, err) && assert.Len(t, builds, 1) {
+		assert.Equal(t, "admin", builds[0].guide.Name)
+	}
+	_, err = projectBuilds(conf, []string{"none"})
+	assert.EqualError(t, err, "unknown guide: none")
+	_, err = projectBuilds(&settings.Config{}, nil)
+	assert.Error(t, err)
+}
+
+func TestPrintSummary(t *testing.T) {
+	builds := []*guideBuild{
+		{guide: &settings.Guide{Name: "user"}, files: 12, updated: 3, warnings: 1},
+		{guide: &settings.Guide{Name: "admin"}, errors: 5, err: errors.New("too many parse errors")},
+	}
+	var w strings.Builder
+	assert.Equal(t, 1, printSummary(&w, builds, 1500*time.Millisecond))
+	assert.Equal(t, `guide  files  updated  errors  warnings  status
+user   12     3        0       1         ok
+admin  0      0        5       0         failed: too many parse errors
+1 guide(s) built, 1 failed in 1.5s
+`, w.String())
+	assert.Equal(t, 0, printSummary(&w, builds[:1], 0))
+}
+
+func TestRunJobs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	project := &settings.Project{SrcDir: dir, DestDir: filepath.Join(dir, "docs"), ArtifactsDir: dir}
+	crossLinks := make(map[string]string)
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("guide%d", i)
+		//every guide links to the next one
+		src := fmt.Sprintf("= Guide %d\n\n[[g%d]]\n== One\n\nsee <<guide%d.adoc#g%d,next>>\n\n== Two\n", i, i, (i+1)%6, (i+1)%6)
+		if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".adoc"), []byte(src), 0666)) {
+			return
+		}
+		project.Guides = append(project.Guides, &settings.Guide{Name: name, Src: name + ".adoc", Dest: name})
+		crossLinks[name+".adoc"] = name + "/"
+	}
+	builds, err := projectBuilds(&settings.Config{Project: project, CrossLinks: crossLinks}, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	idMaps := idmap.New(idmap.NewMemory())
+	for _, b := range builds {
+		b.idMaps = idMaps
+		b.log = makeLog(&b.out, false)
+	}
+	runJobs(builds, 3, (*guideBuild).genIdMap)
+	runJobs(builds, 3, (*guideBuild).convert)
+	for i, b := range builds {
+		if !assert.NoError(t, b.err, b.guide.Name) {
+			continue
+		}
+		assert.Equal(t, 2, b.files, b.guide.Name)
+		data, err := ioutil.ReadFile(filepath.Join(dir, "docs", b.guide.Name, b.guide.Name+"_1.md"))
+		assert.NoError(t, err, b.guide.Name)
+		assert.Contains(t, string(data), fmt.Sprintf("[next](../guide%d/guide%d_1.md#g%d)", (i+1)%6, (i+1)%6, (i+1)%6))
+		//logs are written by runJobs
+		assert.Equal(t, 0, b.out.Len())
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"asciidoc2md/ast"
+	"strconv"
+)
+
+const defaultTocLevels = 2
+
+// tocItem is a header of the table of contents with its output file
+type tocItem struct {
+	Header *ast.Header
+	File   string //output file name
+	Url    string //"file.md#id" link to the header
+}
+
+// appendTocItem adds a header to the table of contents,
+// should be called in fillIdMap after the header is added to the idmap
+func (fs *FileSplitter) appendTocItem(h *ast.Header) {
+	if h.Float {
+		//float headers are not the part of the table of contents
+		return
+	}
+	id := h.Id
+	if id == "" {
+		id = permLink(h.Title())
+	}
+	url := fs.fileName + "#" + id
+	if entry := fs.idMaps[fs.doc.Name][id]; entry != nil {
+		url = entry.FileName + "#" + id
+	}
+	fs.toc = append(fs.toc, tocItem{Header: h, File: fs.fileName, Url: url})
+}
+
+// tocLevels returns the maximum header level of the table of contents,
+// ":toclevels:" counts section levels, so "==" header is level 1
+func (fs *FileSplitter) tocLevels() int {
+	levels := defaultTocLevels
+	if v, err := strconv.Atoi(fs.doc.Attributes["toclevels"]); err == nil {
+		levels = v
+	}
+	return levels + 1
+}
+
+// buildToc builds the tree of the table of contents from the flat list of the headers
+func buildToc(items []tocItem, maxLevel int) []*ast.TocEntry {
+	var res []*ast.TocEntry
+	var stack []*ast.TocEntry
+	for _, it := range items {
+		if it.Header.Level < 2 || it.Header.Level > maxLevel {
+			//document title isn't the part of the table of contents
+			continue
+		}
+		e := &ast.TocEntry{Level: it.Header.Level, Text: it.Header.Title(), Url: it.Url}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= e.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			res = append(res, e)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Entries = append(parent.Entries, e)
+		}
+		stack = append(stack, e)
+	}
+	return res
+}
+
+// localToc returns entries of the headers which are in the same file and below the header h
+func (fs *FileSplitter) localToc(h *ast.Header) []*ast.TocEntry {
+	var items []tocItem
+	for i, it := range fs.toc {
+		if it.Header != h {
+			continue
+		}
+		for _, next := range fs.toc[i+1:] {
+			if next.File != it.File || next.Header.Level <= h.Level {
+				break
+			}
+			items = append(items, next)
+		}
+		break
+	}
+	return buildToc(items, fs.tocLevels())
+}
+
+// fillToc fills "toc::[]" macros with the document table of contents. If ":toc:" attribute is set,
+// but its value isn't "macro", then local table of contents is inserted after every split header.
+func (fs *FileSplitter) fillToc() {
+	entries := buildToc(fs.toc, fs.tocLevels())
+	fs.doc.Walk(func(b ast.Block, root *ast.Document) bool {
+		if t, ok := b.(*ast.Toc); ok {
+			t.Entries = entries
+		}
+		return true
+	}, nil)
+	if placement, ok := fs.doc.Attributes["toc"]; ok && placement != "macro" {
+		fs.insertLocalTocs(fs.doc)
+	}
+}
+
+func (fs *FileSplitter) insertLocalTocs(doc *ast.Document) {
+	blocks := make([]ast.Block, 0, len(doc.Blocks))
+	for _, b := range doc.Blocks {
+		blocks = append(blocks, b)
+		switch b.(type) {
+		case *ast.Header:
+			h := b.(*ast.Header)
+			if h.Level != fs.level || h.Float {
+				continue
+			}
+			if entries := fs.localToc(h); len(entries) > 0 {
+				blocks = append(blocks, &ast.Toc{Title: fs.doc.Attributes["toc-title"], Entries: entries})
+			}
+		case *ast.Document:
+			//include
+			fs.insertLocalTocs(b.(*ast.Document))
+		}
+	}
+	doc.Blocks = blocks
+}
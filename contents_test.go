@@ -0,0 +1,97 @@
+package main
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/parser"
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const tocTestInput = `= Doc
+:toc: macro
+:toclevels: 2
+
+toc::[]
+
+== Header2
+
+[discrete]
+=== Float header
+
+=== Header3
+
+==== Header4
+
+== Header5
+`
+
+func TestSplitter_TocMacro(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	doc, err := parser.New(tocTestInput, nil, logger).Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	splitter := NewFileSplitter(doc, "slug", testConf(), ".", 2, logger)
+	splitter.init(false)
+
+	toc, ok := doc.Blocks[1].(*ast.Toc)
+	if !assert.True(t, ok) {
+		return
+	}
+	exp := []*ast.TocEntry{
+		{Level: 2, Text: "Header2", Url: "slug_1.md#header2", Entries: []*ast.TocEntry{
+			{Level: 3, Text: "Header3", Url: "slug_1.md#header3"},
+		}},
+		{Level: 2, Text: "Header5", Url: "slug_2.md#header5"},
+	}
+	assert.Equal(t, exp, toc.Entries)
+}
+
+func TestSplitter_LocalToc(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	input := "= Doc\n:toc:\n:toclevels: 3\n\n== Header2\n\n=== Header3\n\n==== Header4\n\n== Header5\n"
+	doc, err := parser.New(input, nil, logger).Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	splitter := NewFileSplitter(doc, "slug", testConf(), ".", 2, logger)
+	splitter.init(false)
+
+	var tocs []*ast.Toc
+	doc.Walk(func(b ast.Block, root *ast.Document) bool {
+		if t, ok := b.(*ast.Toc); ok {
+			tocs = append(tocs, t)
+		}
+		return true
+	}, nil)
+	//"Header5" has no subheaders, so it has no local toc
+	if !assert.Len(t, tocs, 1) {
+		return
+	}
+	exp := []*ast.TocEntry{
+		{Level: 3, Text: "Header3", Url: "slug_1.md#header3", Entries: []*ast.TocEntry{
+			{Level: 4, Text: "Header4", Url: "slug_1.md#header4"},
+		}},
+	}
+	assert.Equal(t, exp, tocs[0].Entries)
+}
+
+func TestSplitter_SectionNumbers(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	input := "= Doc\n:sectnums:\n\ntoc::[]\n\n== Header2\n\n[appendix]\n== Extra\n"
+	doc, err := parser.New(input, nil, logger).Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	splitter := NewFileSplitter(doc, "slug", testConf(), ".", 2, logger)
+	splitter.init(false)
+
+	assert.Equal(t, &IdMapEntry{FileName: "slug_1.md", Caption: "1. Header2"}, splitter.idMaps["gotest.adoc"]["1-header2"])
+	assert.Equal(t, &IdMapEntry{FileName: "slug_2.md", Caption: "Appendix A. Extra"}, splitter.idMaps["gotest.adoc"]["appendix-a-extra"])
+	toc := doc.Blocks[1].(*ast.Toc)
+	if assert.Len(t, toc.Entries, 2) {
+		assert.Equal(t, "Appendix A. Extra", toc.Entries[1].Text)
+	}
+}
@@ -0,0 +1,125 @@
+// Package diag collects the parsing and conversion diagnostics with their source positions.
+package diag
+
+import (
+	"asciidoc2md/ast"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is the message about the source range, it's also used as the error with the position
+type Diagnostic struct {
+	Pos      ast.Pos
+	Severity Severity
+	Err      error
+}
+
+// Errorf creates an error diagnostic
+func Errorf(pos ast.Pos, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{Pos: pos, Err: fmt.Errorf(format, args...)}
+}
+
+// Warnf creates a warning diagnostic
+func Warnf(pos ast.Pos, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{Pos: pos, Severity: Warning, Err: fmt.Errorf(format, args...)}
+}
+
+// Error returns "file.adoc:12:5: message"
+func (d *Diagnostic) Error() string {
+	if loc := d.Pos.Location(); loc != "" {
+		return loc + ": " + d.Err.Error()
+	}
+	return d.Err.Error()
+}
+
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Collector accumulates the diagnostics, the zero value is ready to use
+type Collector struct {
+	list []*Diagnostic
+}
+
+func (c *Collector) Add(d *Diagnostic) {
+	c.list = append(c.list, d)
+}
+
+// Report adds the error, errors without the position get pos
+func (c *Collector) Report(err error, pos ast.Pos) {
+	var d *Diagnostic
+	if !errors.As(err, &d) {
+		d = &Diagnostic{Pos: pos, Err: err}
+	}
+	c.Add(d)
+}
+
+// Diagnostics returns the diagnostics in the order they were added
+func (c *Collector) Diagnostics() []*Diagnostic {
+	return c.list
+}
+
+// Count returns the number of the diagnostics with the severity
+func (c *Collector) Count(s Severity) int {
+	var n int
+	for _, d := range c.list {
+		if d.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Print writes the diagnostics grouped by file and sorted by position, the summary line is written at the end
+func (c *Collector) Print(w io.Writer) {
+	if len(c.list) == 0 {
+		return
+	}
+	list := make([]*Diagnostic, len(c.list))
+	copy(list, c.list)
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Pos, list[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	file := "\x00"
+	for _, d := range list {
+		if d.Pos.File != file {
+			file = d.Pos.File
+			name := file
+			if name == "" {
+				name = "<unknown>"
+			}
+			fmt.Fprintf(w, "%s:\n", name)
+		}
+		pos := d.Pos
+		pos.File = ""
+		if loc := pos.Location(); loc != "" {
+			fmt.Fprintf(w, "  %s: %s: %s\n", loc, d.Severity, d.Err)
+		} else {
+			fmt.Fprintf(w, "  %s: %s\n", d.Severity, d.Err)
+		}
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", c.Count(Error), c.Count(Warning))
+}
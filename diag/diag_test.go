@@ -0,0 +1,33 @@
+package diag
+
+import (
+	"asciidoc2md/ast"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCollector(t *testing.T) {
+	var c Collector
+	c.Add(Errorf(ast.Pos{File: "b.adoc", Line: 3, Col: 1}, "invalid %s", "table"))
+	c.Add(Warnf(ast.Pos{File: "a.adoc", Line: 10, Col: 5}, "empty title"))
+	c.Report(errors.New("cannot read include"), ast.Pos{File: "a.adoc", Line: 2, Col: 1})
+	wrapped := Errorf(ast.Pos{File: "b.adoc", Line: 1, Col: 1}, "bad")
+	c.Report(wrapped, ast.Pos{File: "c.adoc", Line: 5})
+	assert.Equal(t, 3, c.Count(Error))
+	assert.Equal(t, 1, c.Count(Warning))
+	assert.Same(t, wrapped, c.Diagnostics()[3])
+	assert.Equal(t, "b.adoc:3:1: invalid table", c.Diagnostics()[0].Error())
+
+	w := strings.Builder{}
+	c.Print(&w)
+	assert.Equal(t, `a.adoc:
+  2:1: error: cannot read include
+  10:5: warning: empty title
+b.adoc:
+  1:1: error: bad
+  3:1: error: invalid table
+3 error(s), 1 warning(s)
+`, w.String())
+}
@@ -0,0 +1,523 @@
+package html
+
+import (
+	"asciidoc2md/asciimath"
+	"asciidoc2md/ast"
+	"asciidoc2md/token"
+	"cdr.dev/slog"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+type GetWriterFunc func(*ast.Header) io.Writer
+
+// Converter writes the document as semantic html5: sections, figures, admonition divs, tables with spans
+// and code blocks with "language-*" classes.
+type Converter struct {
+	imageFolder string
+	log         slog.Logger
+	writerFunc  GetWriterFunc
+	writer      io.Writer
+	sections    []int //levels of the open sections
+}
+
+func New(imFolder string, logger slog.Logger, writerFunc GetWriterFunc) *Converter {
+	return &Converter{imageFolder: imFolder,
+		log:        logger,
+		writerFunc: writerFunc}
+}
+
+// RenderHtml writes the html content of the document, page layout isn't written
+func (c *Converter) RenderHtml(doc *ast.Document, w io.Writer) {
+	c.writer = w
+	c.WriteDocument(doc)
+	c.closeSections(0)
+}
+
+func (c *Converter) WriteDocument(doc *ast.Document) {
+	c.WriteContainerBlock(&doc.ContainerBlock)
+}
+
+func (c *Converter) WriteString(s string) {
+	c.writer.Write([]byte(s))
+}
+
+// closeSections closes the open sections of the specified level and deeper
+func (c *Converter) closeSections(level int) {
+	for len(c.sections) > 0 && c.sections[len(c.sections)-1] >= level {
+		c.WriteString("</section>\n")
+		c.sections = c.sections[:len(c.sections)-1]
+	}
+}
+
+func (c *Converter) WriteContainerBlock(p *ast.ContainerBlock) {
+	var title *ast.BlockTitle //title of the next block
+	for _, b := range p.Blocks {
+		switch b.(type) {
+		case *ast.BlockTitle:
+			if title != nil {
+				c.WriteBlockTitle(title)
+			}
+			title = b.(*ast.BlockTitle)
+			continue
+		case *ast.Image:
+			c.WriteImage(b.(*ast.Image), title)
+		case *ast.Table:
+			c.WriteTable(b.(*ast.Table), title)
+		case *ast.SyntaxBlock:
+			c.WriteSyntaxBlock(b.(*ast.SyntaxBlock), title)
+		default:
+			if title != nil {
+				c.WriteBlockTitle(title)
+			}
+			c.WriteBlock(b)
+		}
+		title = nil
+	}
+	if title != nil {
+		c.WriteBlockTitle(title)
+	}
+}
+
+func (c *Converter) WriteBlock(b ast.Block) {
+	switch b.(type) {
+	case *ast.Header:
+		c.WriteHeader(b.(*ast.Header))
+	case *ast.Document:
+		//include
+		c.WriteDocument(b.(*ast.Document))
+	case *ast.ContainerBlock:
+		c.WriteContainerBlock(b.(*ast.ContainerBlock))
+	case *ast.ListBlock:
+		c.WriteContainerBlock(&b.(*ast.ListBlock).ContainerBlock)
+	case *ast.HorLine:
+		c.WriteString("<hr>\n")
+	case *ast.Video:
+		c.WriteVideo(b.(*ast.Video))
+	case *ast.Audio:
+		c.WriteAudio(b.(*ast.Audio))
+	case *ast.Paragraph:
+		c.WriteString("<p>")
+		c.WriteParagraph(b.(*ast.Paragraph))
+		c.WriteString("</p>\n")
+	case *ast.List:
+		c.WriteList(b.(*ast.List))
+	case *ast.Admonition:
+		a := b.(*ast.Admonition)
+		c.writeAdmonition(a.Kind, a.Content)
+	case *ast.ExampleBlock:
+		c.WriteExampleBlock(b.(*ast.ExampleBlock))
+	case *ast.Bookmark:
+		c.WriteString(fmt.Sprintf(`<a id="%s"></a>`+"\n", escape(b.(*ast.Bookmark).Literal)))
+	case *ast.Comment:
+		//comments aren't written
+	case *ast.Toc:
+		c.WriteToc(b.(*ast.Toc))
+	case *ast.Stem:
+		s := b.(*ast.Stem)
+		c.WriteString(`<div class="stem">\[` + escape(c.latex(s.Pos, s.Notation, s.Text)) + `\]</div>` + "\n")
+	case *ast.PassBlock:
+		c.WriteString(strings.TrimRight(b.(*ast.PassBlock).Literal, "\n") + "\n")
+	case *ast.Unknown:
+		//the source the parser failed to parse
+		c.WriteString(`<pre class="unknown">` + escape(b.(*ast.Unknown).Text) + "</pre>\n")
+	case *ast.DiagramBlock:
+		c.WriteDiagram(b.(*ast.DiagramBlock))
+	default:
+		c.log.Error(context.Background(), "unsupported block", slog.F("pos", ast.Location(b)), slog.F("block", b.StringWithIndent("")))
+	}
+}
+
+// headerIdRE is used to generate header ids the same way as markdown "toc" extension does
+var headerIdRE = regexp.MustCompile(`[^a-яА-Яa-zA-Z0-9]+`)
+
+func headerId(h *ast.Header) string {
+	if h.Id != "" {
+		return h.Id
+	}
+	return headerIdRE.ReplaceAllLiteralString(strings.ToLower(h.Title()), "-")
+}
+
+// WriteHeader opens a section of the header level, the open sections of the same or deeper level are closed.
+// The header is written into the new writer if writer function returns it.
+func (c *Converter) WriteHeader(h *ast.Header) {
+	level := h.Level
+	if level > 6 {
+		level = 6
+	}
+	if h.Float {
+		c.WriteString(fmt.Sprintf(`<h%v id="%s" class="discrete">%s</h%v>`+"\n", level, escape(headerId(h)), formatText(h.Text, true), level))
+		return
+	}
+	c.closeSections(h.Level)
+	if c.writerFunc != nil {
+		if w := c.writerFunc(h); w != nil {
+			c.closeSections(0)
+			c.writer = w
+		}
+	}
+	if h.Level < 2 {
+		//document title
+		c.WriteString(fmt.Sprintf(`<h1 id="%s">%s</h1>`+"\n", escape(headerId(h)), formatText(h.Title(), true)))
+		return
+	}
+	c.sections = append(c.sections, h.Level)
+	c.WriteString(fmt.Sprintf(`<section class="sect%v">`+"\n", h.Level-1))
+	c.WriteString(fmt.Sprintf(`<h%v id="%s">%s</h%v>`+"\n", level, escape(headerId(h)), formatText(h.Title(), true), level))
+}
+
+func (c *Converter) WriteBlockTitle(t *ast.BlockTitle) {
+	c.WriteString(`<div class="title">` + formatText(t.Title, true) + "</div>\n")
+}
+
+// listTypes are html "type" attribute values of the ordered list numbering styles
+var listTypes = map[string]string{
+	"loweralpha": "a",
+	"upperalpha": "A",
+	"lowerroman": "i",
+	"upperroman": "I",
+}
+
+func (c *Converter) WriteList(l *ast.List) {
+	if l.Definition {
+		c.WriteDefinitionList(l)
+		return
+	}
+	tag := "ul"
+	var attrs string
+	if l.Numbered || l.Callouts {
+		tag = "ol"
+		if l.Start != 0 {
+			attrs += fmt.Sprintf(` start="%v"`, l.Start)
+		}
+		if l.Reversed {
+			attrs += " reversed"
+		}
+		if t, ok := listTypes[l.Style]; ok {
+			attrs += fmt.Sprintf(` type="%s"`, t)
+		}
+	}
+	switch {
+	case l.Callouts:
+		attrs += ` class="callouts"`
+	case l.Checklist:
+		attrs += ` class="checklist"`
+	case l.Bibliography:
+		attrs += ` class="bibliography"`
+	}
+	c.WriteString("<" + tag + attrs + ">\n")
+	for _, item := range l.Items {
+		c.WriteString("<li>")
+		c.writeCompact(item)
+		c.WriteString("</li>\n")
+	}
+	c.WriteString("</" + tag + ">\n")
+}
+
+// WriteDefinitionList writes "term:: definition" list, the first paragraph of every item is the term
+func (c *Converter) WriteDefinitionList(l *ast.List) {
+	c.WriteString("<dl>\n")
+	for _, item := range l.Items {
+		blocks := item.Blocks
+		if len(blocks) > 0 {
+			if par, ok := blocks[0].(*ast.Paragraph); ok {
+				c.WriteString("<dt>")
+				c.WriteParagraph(trimTerm(par))
+				c.WriteString("</dt>\n")
+				blocks = blocks[1:]
+			}
+		}
+		c.WriteString("<dd>")
+		c.writeCompact(&ast.ContainerBlock{Blocks: blocks})
+		c.WriteString("</dd>\n")
+	}
+	c.WriteString("</dl>\n")
+}
+
+// trimTerm removes "::" and ";;" definition list markers from the term paragraph
+func trimTerm(par *ast.Paragraph) *ast.Paragraph {
+	res := *par
+	res.Blocks = append([]ast.Block{}, par.Blocks...)
+	if len(res.Blocks) > 0 {
+		if t, ok := res.Blocks[len(res.Blocks)-1].(*ast.Text); ok {
+			res.Blocks[len(res.Blocks)-1] = &ast.Text{Text: strings.TrimRight(t.Text, ":; \t")}
+		}
+	}
+	return &res
+}
+
+// writeCompact writes the single paragraph as inline content, without "<p>" tag
+func (c *Converter) writeCompact(cb *ast.ContainerBlock) {
+	if len(cb.Blocks) == 1 {
+		if par, ok := cb.Blocks[0].(*ast.Paragraph); ok {
+			c.WriteParagraph(par)
+			return
+		}
+	}
+	c.WriteString("\n")
+	c.WriteContainerBlock(cb)
+}
+
+func (c *Converter) WriteTable(t *ast.Table, title *ast.BlockTitle) {
+	c.WriteString("<table>\n")
+	if title != nil {
+		c.WriteString("<caption>" + formatText(title.Title, true) + "</caption>\n")
+	}
+	grid := t.Grid()
+	for r, row := range grid {
+		header := t.Header && r == 0
+		tag := "td"
+		switch {
+		case header:
+			tag = "th"
+			c.WriteString("<thead>\n")
+		case r == 0 || (t.Header && r == 1):
+			c.WriteString("<tbody>\n")
+		}
+		c.WriteString("<tr>")
+		for _, i := range row {
+			if i == -1 {
+				//covered by a spanned cell
+				continue
+			}
+			var attrs string
+			span := t.Span(i)
+			if span.Cols > 1 {
+				attrs += fmt.Sprintf(` colspan="%v"`, span.Cols)
+			}
+			if span.Rows > 1 {
+				attrs += fmt.Sprintf(` rowspan="%v"`, span.Rows)
+			}
+			c.WriteString("<" + tag + attrs + ">")
+			c.writeCompact(t.Cells[i])
+			c.WriteString("</" + tag + ">")
+		}
+		c.WriteString("</tr>\n")
+		if header {
+			c.WriteString("</thead>\n")
+		}
+	}
+	if len(grid) > 1 || (len(grid) == 1 && !t.Header) {
+		c.WriteString("</tbody>\n")
+	}
+	c.WriteString("</table>\n")
+}
+
+// imagePath returns the path of the image or the media file in the images folder
+func (c *Converter) imagePath(path string) string {
+	if strings.Contains(path, "://") {
+		//remote file
+		return path
+	}
+	return c.imageFolder + strings.ReplaceAll(path, `\`, `/`)
+}
+
+func (c *Converter) WriteImage(im *ast.Image, title *ast.BlockTitle) {
+	c.WriteString(fmt.Sprintf(`<figure><img src="%s" alt="">`, escape(c.imagePath(im.Path))))
+	if title != nil {
+		c.WriteString("<figcaption>" + formatText(title.Title, true) + "</figcaption>")
+	}
+	c.WriteString("</figure>\n")
+}
+
+func (c *Converter) WriteVideo(v *ast.Video) {
+	var src string
+	switch v.Provider {
+	case "youtube":
+		src = "https://www.youtube.com/embed/" + v.Target
+	case "vimeo":
+		src = "https://player.vimeo.com/video/" + v.Target
+	default:
+		c.WriteString(fmt.Sprintf(`<video src="%s" controls>Your browser does not support the video tag.</video>`+"\n",
+			escape(c.imagePath(v.Target))))
+		return
+	}
+	c.WriteString(fmt.Sprintf(`<iframe src="%s" frameborder="0" allowfullscreen></iframe>`+"\n", escape(src)))
+}
+
+func (c *Converter) WriteAudio(a *ast.Audio) {
+	c.WriteString(fmt.Sprintf(`<audio src="%s" controls>Your browser does not support the audio tag.</audio>`+"\n",
+		escape(c.imagePath(a.Target))))
+}
+
+// writeAdmonition writes "<div class="admonition note">" block
+func (c *Converter) writeAdmonition(kind string, content *ast.ContainerBlock) {
+	kind = strings.ToLower(kind)
+	c.WriteString(fmt.Sprintf(`<div class="admonition %s">`+"\n", kind))
+	c.WriteString(fmt.Sprintf(`<p class="admonition-title">%s</p>`+"\n", strings.Title(kind)))
+	c.WriteContainerBlock(content)
+	c.WriteString("</div>\n")
+}
+
+func (c *Converter) WriteExampleBlock(ex *ast.ExampleBlock) {
+	switch {
+	case ex.Collapsible:
+		summary := "Details"
+		if ex.Kind != "" {
+			summary = strings.Title(strings.ToLower(ex.Kind))
+		}
+		c.WriteString("<details>\n<summary>" + summary + "</summary>\n")
+		c.WriteContainerBlock(&ex.ContainerBlock)
+		c.WriteString("</details>\n")
+	case ex.Kind != "":
+		c.writeAdmonition(ex.Kind, &ex.ContainerBlock)
+	case ex.Delim.Type == token.SIDEBAR:
+		c.WriteString(`<aside class="sidebar">` + "\n")
+		c.WriteContainerBlock(&ex.ContainerBlock)
+		c.WriteString("</aside>\n")
+	case ex.Delim.Type == token.QUOTE_BLOCK:
+		c.WriteString("<blockquote>\n")
+		c.WriteContainerBlock(&ex.ContainerBlock)
+		c.WriteString("</blockquote>\n")
+	default:
+		c.WriteString(`<div class="example">` + "\n")
+		c.WriteContainerBlock(&ex.ContainerBlock)
+		c.WriteString("</div>\n")
+	}
+}
+
+var calloutRE = regexp.MustCompile(`&lt;(\.|\d+)&gt;`)
+
+func (c *Converter) WriteSyntaxBlock(sb *ast.SyntaxBlock, title *ast.BlockTitle) {
+	if title != nil {
+		c.WriteBlockTitle(title)
+	}
+	str := escape(strings.Trim(sb.Literal, "\n"))
+	if sb.InlineHighlight {
+		//"pass:quotes[#some_text#]" highlighting
+		str = strings.ReplaceAll(str, `pass:quotes[#`, `<mark>`)
+		str = strings.ReplaceAll(str, `#]`, `</mark>`)
+	}
+	var i int
+	str = calloutRE.ReplaceAllStringFunc(str, func(s string) string {
+		i++
+		return fmt.Sprintf(`<b class="conum">(%v)</b>`, i)
+	})
+	var class string
+	if sb.Lang != "" {
+		class = fmt.Sprintf(` class="language-%s"`, escape(sb.Lang))
+	}
+	c.WriteString(fmt.Sprintf("<pre><code%s>%s</code></pre>\n", class, str))
+}
+
+// WriteDiagram writes mermaid diagrams to be rendered by mermaid.js, other diagrams are written as code blocks
+func (c *Converter) WriteDiagram(d *ast.DiagramBlock) {
+	str := escape(strings.Trim(d.Literal, "\n"))
+	if d.Type == "mermaid" {
+		c.WriteString(`<pre class="mermaid">` + str + "</pre>\n")
+		return
+	}
+	c.WriteString(fmt.Sprintf(`<pre><code class="language-%s">%s</code></pre>`+"\n", d.Type, str))
+}
+
+func (c *Converter) WriteToc(t *ast.Toc) {
+	c.WriteString(`<nav class="toc">` + "\n")
+	if t.Title != "" {
+		c.WriteString(`<div class="title">` + formatText(t.Title, true) + "</div>\n")
+	}
+	c.writeTocEntries(t.Entries)
+	c.WriteString("</nav>\n")
+}
+
+func (c *Converter) writeTocEntries(entries []*ast.TocEntry) {
+	c.WriteString("<ul>\n")
+	for _, e := range entries {
+		c.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a>`, escape(linkUrl(e.Url)), formatText(e.Text, true)))
+		if len(e.Entries) > 0 {
+			c.WriteString("\n")
+			c.writeTocEntries(e.Entries)
+		}
+		c.WriteString("</li>\n")
+	}
+	c.WriteString("</ul>\n")
+}
+
+// latex returns the formula in LaTeX notation, AsciiMath formula is left as is if it cannot be converted
+func (c *Converter) latex(pos ast.Pos, notation string, text string) string {
+	if notation != ast.AsciiMath {
+		return text
+	}
+	tex, err := asciimath.ToLatex(text)
+	if err != nil {
+		c.log.Warn(context.Background(), "cannot convert AsciiMath to LaTeX", slog.F("pos", pos.Location()), slog.F("formula", text), slog.Error(err))
+		return text
+	}
+	return tex
+}
+
+func (c *Converter) WriteParagraph(p *ast.Paragraph) {
+	for _, b := range p.Blocks {
+		switch b.(type) {
+		case *ast.Text:
+			c.WriteString(formatText(b.(*ast.Text).Text, !p.NoReplacements))
+		case *ast.CheckBox:
+			if b.(*ast.CheckBox).Checked {
+				c.WriteString(`<input type="checkbox" checked disabled> `)
+			} else {
+				c.WriteString(`<input type="checkbox" disabled> `)
+			}
+		case *ast.LineBreak:
+			c.WriteString("<br>\n")
+		case *ast.IndexTerm:
+			//concealed terms are only listed in the index
+			if t := b.(*ast.IndexTerm); t.Visible {
+				c.WriteString(formatText(t.Terms[0], true))
+			}
+		case *ast.Bookmark:
+			c.WriteString(fmt.Sprintf(`<a id="%s"></a>`, escape(b.(*ast.Bookmark).Literal)))
+		case *ast.BibAnchor:
+			ba := b.(*ast.BibAnchor)
+			c.WriteString(fmt.Sprintf(`<a id="%s"></a>[%s]`, escape(ba.Id), formatText(ba.Label, true)))
+		case *ast.InlineStem:
+			s := b.(*ast.InlineStem)
+			c.WriteString(`\(` + escape(c.latex(s.Pos, s.Notation, s.Text)) + `\)`)
+		case *ast.Kbd:
+			keys := make([]string, 0, len(b.(*ast.Kbd).Keys))
+			for _, k := range b.(*ast.Kbd).Keys {
+				keys = append(keys, "<kbd>"+escape(k)+"</kbd>")
+			}
+			c.WriteString(strings.Join(keys, "+"))
+		case *ast.Button:
+			c.WriteString(`<b class="button">` + escape(b.(*ast.Button).Text) + "</b>")
+		case *ast.Menu:
+			items := make([]string, 0, len(b.(*ast.Menu).Items))
+			for _, item := range b.(*ast.Menu).Items {
+				items = append(items, "<b>"+escape(item)+"</b>")
+			}
+			c.WriteString(`<span class="menuseq">` + strings.Join(items, "&#160;&#8250; ") + "</span>")
+		case *ast.InlineImage:
+			c.WriteString(fmt.Sprintf(`<img src="%s" alt="">`, escape(c.imagePath(b.(*ast.InlineImage).Path))))
+		case *ast.Link:
+			c.WriteLink(b.(*ast.Link))
+		}
+	}
+}
+
+func (c *Converter) WriteLink(l *ast.Link) {
+	caption := l.Text
+	if caption == "" {
+		caption = l.Url
+	}
+	var attrs string
+	if len(l.Roles) > 0 {
+		attrs += fmt.Sprintf(` class="%s"`, escape(strings.Join(l.Roles, " ")))
+	}
+	if l.Window != "" {
+		attrs += fmt.Sprintf(` target="%s"`, escape(l.Window))
+		if l.Window == "_blank" {
+			attrs += ` rel="noopener"`
+		}
+	}
+	c.WriteString(fmt.Sprintf(`<a href="%s"%s>%s</a>`, escape(linkUrl(l.Url)), attrs, formatText(caption, true)))
+}
+
+// mdLinkRE matches links to markdown files of the other documents: "../user/guide_2.md#id"
+var mdLinkRE = regexp.MustCompile(`^([^:#?]*)\.md(#.*)?$`)
+
+// linkUrl replaces ".md" extension of the links to the split files with ".html"
+func linkUrl(url string) string {
+	return mdLinkRE.ReplaceAllString(url, "$1.html$2")
+}
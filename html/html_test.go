@@ -0,0 +1,109 @@
+package html
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/parser"
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogtest"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+var cases = []struct {
+	name  string
+	input string
+	exp   string
+}{
+	{
+		name:  "sections",
+		input: "== One\n\ntext\n\n=== Two\n\n== Three",
+		exp: `<section class="sect1">
+<h2 id="one">One</h2>
+<p>text</p>
+<section class="sect2">
+<h3 id="two">Two</h3>
+</section>
+</section>
+<section class="sect1">
+<h2 id="three">Three</h2>
+</section>
+`,
+	},
+	{
+		name:  "inline formatting",
+		input: "Some *bold*, _italic_ and `*code* <tag>` text -- (C) +++<br>+++",
+		exp:   "<p>Some <strong>bold</strong>, <em>italic</em> and <code><strong>code</strong> &lt;tag&gt;</code> text — © <br></p>\n",
+	},
+	{
+		name:  "figure",
+		input: ".Diagram\nimage::diagram.png[]",
+		exp:   `<figure><img src="images/diagram.png" alt=""><figcaption>Diagram</figcaption></figure>` + "\n",
+	},
+	{
+		name:  "admonition",
+		input: "WARNING: Be careful",
+		exp: `<div class="admonition warning">
+<p class="admonition-title">Warning</p>
+<p>Be careful</p>
+</div>
+`,
+	},
+	{
+		name:  "table spans",
+		input: "[%header]\n|===\n|A |B\n.2+|tall |b\n|c\n|===",
+		exp: `<table>
+<thead>
+<tr><th>A </th><th>B</th></tr>
+</thead>
+<tbody>
+<tr><td rowspan="2">tall </td><td>b</td></tr>
+<tr><td>c</td></tr>
+</tbody>
+</table>
+`,
+	},
+	{
+		name:  "code block",
+		input: "[source,sql]\n----\nselect '<b>' <1>\n----",
+		exp:   `<pre><code class="language-sql">select '&lt;b&gt;' <b class="conum">(1)</b></code></pre>` + "\n",
+	},
+	{
+		name:  "links",
+		input: "See link:https://example.org[example^] and link:guide_2.md#intro[intro].",
+		exp:   `<p>See <a href="https://example.org" target="_blank" rel="noopener">example</a> and <a href="guide_2.html#intro">intro</a>.</p>` + "\n",
+	},
+}
+
+func TestAll(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	for _, c := range cases {
+		doc, err := parser.New(c.input, nil, logger).Parse("test.adoc")
+		if !assert.NoError(t, err, c.name) {
+			continue
+		}
+		w := strings.Builder{}
+		New("images/", logger, nil).RenderHtml(doc, &w)
+		assert.Equal(t, c.exp, w.String(), c.name)
+	}
+}
+
+func TestSplitWriter(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	doc, err := parser.New("== One\n\n=== Sub\n\n== Two", nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var first, second strings.Builder
+	conv := New("", logger, func(h *ast.Header) io.Writer {
+		if h.Text == "Two" {
+			return &second
+		}
+		return nil
+	})
+	conv.RenderHtml(doc, &first)
+	//sections are closed at the end of every page
+	assert.Equal(t, "<section class=\"sect1\">\n<h2 id=\"one\">One</h2>\n<section class=\"sect2\">\n<h3 id=\"sub\">Sub</h3>\n</section>\n</section>\n", first.String())
+	assert.Equal(t, "<section class=\"sect1\">\n<h2 id=\"two\">Two</h2>\n</section>\n", second.String())
+}
@@ -0,0 +1,62 @@
+package html
+
+import (
+	"html/template"
+	"io"
+	"io/ioutil"
+)
+
+// Page is the data of the page layout template
+type Page struct {
+	Title    string        //title of the page header or the document title
+	DocTitle string        //document title
+	Content  template.HTML //rendered page content
+	Prev     string        //file name of the previous page, empty for the first page and in the single page mode
+	Next     string        //file name of the next page, empty for the last page and in the single page mode
+}
+
+// DefaultLayout is used if there is no user page layout
+const DefaultLayout = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 60em; margin: 0 auto; padding: 1em; font-family: sans-serif; line-height: 1.5; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: .3em .6em; vertical-align: top; }
+figure { margin: 1em 0; }
+.admonition { border-left: 4px solid #448aff; padding: 0 1em; margin: 1em 0; }
+.admonition.warning, .admonition.caution { border-color: #ff9100; }
+.admonition-title, .title { font-weight: bold; }
+nav.pages { display: flex; justify-content: space-between; margin-top: 2em; }
+</style>
+</head>
+<body>
+{{.Content}}
+{{if or .Prev .Next}}<nav class="pages">
+{{if .Prev}}<a href="{{.Prev}}">Previous</a>{{else}}<span></span>{{end}}
+{{if .Next}}<a href="{{.Next}}">Next</a>{{end}}
+</nav>{{end}}
+</body>
+</html>
+`
+
+// LoadLayout parses Go html/template page layout from the file, the default layout is used if the file name is empty
+func LoadLayout(file string) (*template.Template, error) {
+	if file == "" {
+		return template.New("page").Parse(DefaultLayout)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return template.New("page").Parse(string(data))
+}
+
+// WritePage writes the page content wrapped into the layout
+func WritePage(layout *template.Template, page *Page, w io.Writer) error {
+	return layout.Execute(w, page)
+}
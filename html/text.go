@@ -0,0 +1,84 @@
+package html
+
+import (
+	"asciidoc2md/subs"
+	"regexp"
+	"strings"
+)
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// escape escapes html special characters, "#" isn't produced so it can't be confused with "#marked text#"
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// "+++passthrough text+++" is written as is
+var passThruRE = regexp.MustCompile(`\+{3}(.+?)\+{3}`)
+// "`monospace`" spans at the word boundaries
+var monoRE = regexp.MustCompile(`\B\x60([^\x60]+)\x60\B`)
+// "++https://example.org++" is the URL which isn't a link
+var escapedURLRE = regexp.MustCompile(`\+\+((?:https?|ftp|irc)://[^\s+]+)\+\+`)
+var smallTextRE = regexp.MustCompile(`\[small]#(.*?)#`)
+
+// constrained formatting marks: the mark is preceded and followed by a whitespace, a punctuation or the line boundary
+var (
+	strongRE = regexp.MustCompile(`(^|[\s[:punct:]])\*([^\s*](?:[^*]*?[^\s*])?)\*($|[\s[:punct:]])`)
+	emRE     = regexp.MustCompile(`(^|[\s[:punct:]])_([^\s_](?:[^_]*?[^\s_])?)_($|[\s[:punct:]])`)
+	markRE   = regexp.MustCompile(`(^|[\s[:punct:]])#([^\s#](?:[^#]*?[^\s#])?)#($|[\s[:punct:]])`)
+)
+
+// unconstrained formatting marks: "**bold**", "__italic__", "##marked##"
+var (
+	uStrongRE = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	uEmRE     = regexp.MustCompile(`__(.+?)__`)
+	uMarkRE   = regexp.MustCompile(`##(.+?)##`)
+)
+
+// formatText converts asciidoc inline formatting to html, replacements ("(C)", "--", "=>", ...)
+// are applied to the text outside of monospace spans if enabled.
+func formatText(s string, replacements bool) string {
+	// replace NBSP with ordinary space
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	s = escapedURLRE.ReplaceAllString(s, "$1")
+	var res strings.Builder
+	beg := 0
+	for _, ind := range passThruRE.FindAllStringSubmatchIndex(s, -1) {
+		res.WriteString(formatSpans(s[beg:ind[0]], replacements))
+		res.WriteString(s[ind[2]:ind[3]])
+		beg = ind[1]
+	}
+	res.WriteString(formatSpans(s[beg:], replacements))
+	return res.String()
+}
+
+// formatSpans formats the text outside and inside of monospace spans
+func formatSpans(s string, replacements bool) string {
+	var res strings.Builder
+	beg := 0
+	for _, ind := range monoRE.FindAllStringSubmatchIndex(s, -1) {
+		res.WriteString(formatMarks(s[beg:ind[0]], replacements))
+		res.WriteString("<code>" + formatMarks(s[ind[2]:ind[3]], false) + "</code>")
+		beg = ind[1]
+	}
+	res.WriteString(formatMarks(s[beg:], replacements))
+	return res.String()
+}
+
+func formatMarks(s string, replacements bool) string {
+	if replacements {
+		s = subs.Replacements(s)
+	}
+	s = escape(s)
+	s = smallTextRE.ReplaceAllString(s, "<small>$1</small>")
+	s = uStrongRE.ReplaceAllString(s, "<strong>$1</strong>")
+	s = uEmRE.ReplaceAllString(s, "<em>$1</em>")
+	s = uMarkRE.ReplaceAllString(s, "<mark>$1</mark>")
+	// the boundary chars are consumed by the match, so adjacent marks "*a* *b*" require the second pass
+	for i := 0; i < 2; i++ {
+		s = strongRE.ReplaceAllString(s, "$1<strong>$2</strong>$3")
+		s = emRE.ReplaceAllString(s, "$1<em>$2</em>$3")
+		s = markRE.ReplaceAllString(s, "$1<mark>$2</mark>$3")
+	}
+	return s
+}
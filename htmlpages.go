@@ -0,0 +1,83 @@
+package main
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/html"
+	"bytes"
+	"cdr.dev/slog"
+	"context"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// RenderHtml writes the document as html pages wrapped into the layout, the pages are split at the same headers
+// as markdown files are. If single is set, the whole document is written into "<slug>.html" file.
+func (fs *FileSplitter) RenderHtml(imagePath string, layout *template.Template, single bool) error {
+	fs.ext = ".html"
+	fs.single = single
+	err := fs.init(false)
+	if err != nil {
+		return err
+	}
+	pages := []*bytes.Buffer{{}}
+	page := 0
+	conv := html.New(imagePath, fs.log, func(header *ast.Header) io.Writer {
+		if header.Level != fs.level || header == fs.firstHeader || header.Float {
+			return nil
+		}
+		page++
+		if fs.single {
+			if fs.fileNames[page] == SkipChapterMark {
+				fs.log.Warn(context.Background(), "skipping chapter", slog.F("pos", header.Location()), slog.F("chapter", header.Text))
+				return ioutil.Discard
+			}
+			return pages[0]
+		}
+		pages = append(pages, &bytes.Buffer{})
+		return pages[page]
+	})
+	conv.RenderHtml(fs.doc, pages[0])
+
+	var docTitle string
+	if fs.doc.Header != nil {
+		docTitle = fs.doc.Header.Title
+	}
+	var names []string //names of the written pages
+	var contents []*bytes.Buffer
+	var titles []string
+	for i, buf := range pages {
+		if fs.fileNames[i] == SkipChapterMark {
+			continue
+		}
+		title := docTitle
+		if h := fs.fileHeaders[i].Header; h != nil && !fs.single {
+			title = h.Title()
+		}
+		names = append(names, fs.fileNames[i])
+		contents = append(contents, buf)
+		titles = append(titles, title)
+	}
+	for i, name := range names {
+		p := &html.Page{Title: titles[i], DocTitle: docTitle, Content: template.HTML(contents[i].String())}
+		if i > 0 {
+			p.Prev = names[i-1]
+		}
+		if i < len(names)-1 {
+			p.Next = names[i+1]
+		}
+		if err = fs.writeHtmlPage(filepath.Join(fs.path, name), layout, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FileSplitter) writeHtmlPage(name string, layout *template.Template, p *html.Page) error {
+	var buf bytes.Buffer
+	if err := html.WritePage(layout, p, &buf); err != nil {
+		return err
+	}
+	return fs.writeFile(name, buf.Bytes())
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"asciidoc2md/html"
+	"asciidoc2md/parser"
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogtest"
+	"github.com/stretchr/testify/assert"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const htmlTestInput = "= Doc\n\n== Header2\n\nSee <<header5>>.\n\n== Header5\n\ntext\n"
+
+func TestSplitter_RenderHtml(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	dir, err := ioutil.TempDir("", "asciidoc2md")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	doc, err := parser.New(htmlTestInput, nil, logger).Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	layout := template.Must(template.New("page").Parse("{{.Title}}|{{.Prev}}|{{.Next}}\n{{.Content}}"))
+	err = NewFileSplitter(doc, "slug", testConf(), dir, 2, logger).RenderHtml("", layout, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	page1, _ := ioutil.ReadFile(filepath.Join(dir, "slug_1.html"))
+	assert.Equal(t, `Header2||slug_2.html
+<h1 id="doc">Doc</h1>
+<section class="sect1">
+<h2 id="header2">Header2</h2>
+<p>See <a href="slug_2.html#header5">Header5</a>.</p>
+</section>
+`, string(page1))
+	page2, _ := ioutil.ReadFile(filepath.Join(dir, "slug_2.html"))
+	assert.Equal(t, `Header5|slug_1.html|
+<section class="sect1">
+<h2 id="header5">Header5</h2>
+<p>text</p>
+</section>
+`, string(page2))
+}
+
+func TestSplitter_RenderHtmlSingle(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	dir, err := ioutil.TempDir("", "asciidoc2md")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	doc, err := parser.New(htmlTestInput, nil, logger).Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	layout, err := html.LoadLayout("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = NewFileSplitter(doc, "slug", testConf(), dir, 2, logger).RenderHtml("", layout, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, "slug.html", files[0].Name())
+	}
+	page, _ := ioutil.ReadFile(filepath.Join(dir, "slug.html"))
+	assert.Contains(t, string(page), `<a href="slug.html#header5">Header5</a>`)
+	assert.Contains(t, string(page), "<title>Doc</title>")
+}
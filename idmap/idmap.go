@@ -0,0 +1,147 @@
+// Package idmap keeps the maps of the header and anchor ids to the output files of the documents,
+// the maps are used to rewrite the links between the documents.
+package idmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Version of the idmap file format, files of the other versions are not loaded
+const Version = 1
+
+var ErrNotFound = errors.New("idmap not found")
+
+type Entry struct {
+	FileName string `yaml:"filename" json:"fileName"`
+	Caption  string `yaml:"caption" json:"caption"`
+}
+
+// Map is header or anchor id -> output file
+type Map map[string]*Entry
+
+// File is the idmap of the document with the options it was generated with
+type File struct {
+	Version    int      `yaml:"version" json:"version"`
+	Doc        string   `yaml:"doc" json:"doc"`
+	Slug       string   `yaml:"slug" json:"slug"`
+	SplitLevel int      `yaml:"split_level" json:"splitLevel"`
+	Sources    []string `yaml:"sources,omitempty" json:"sources,omitempty"` //document and included files
+	SourceHash string   `yaml:"source_hash,omitempty" json:"sourceHash,omitempty"`
+	Ids        Map      `yaml:"ids" json:"ids"`
+}
+
+// NewFile creates the idmap of the document, the hash of the sources is stored to detect stale idmaps
+func NewFile(doc string, slug string, splitLevel int, sources []string, ids Map) (*File, error) {
+	f := &File{Version: Version, Doc: doc, Slug: slug, SplitLevel: splitLevel, Sources: sources, Ids: ids}
+	if len(sources) > 0 {
+		hash, err := Hash(sources)
+		if err != nil {
+			return nil, err
+		}
+		f.SourceHash = hash
+	}
+	return f, nil
+}
+
+// Hash returns sha256 of the files content
+func Hash(files []string) (string, error) {
+	h := sha256.New()
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Stale reports if the sources are changed after the idmap was generated.
+// Idmaps without the sources are never stale.
+func (f *File) Stale() (bool, error) {
+	if len(f.Sources) == 0 {
+		return false, nil
+	}
+	hash, err := Hash(f.Sources)
+	if err != nil {
+		return true, err
+	}
+	return hash != f.SourceHash, nil
+}
+
+func (f *File) check(doc string) error {
+	if f.Version != Version {
+		return fmt.Errorf("idmap of %s has version %d, expected %d: regenerate it", doc, f.Version, Version)
+	}
+	if f.Doc != doc {
+		return fmt.Errorf("idmap of %s is stored for %s", doc, f.Doc)
+	}
+	return nil
+}
+
+// Storage loads and saves the idmaps
+type Storage interface {
+	// Load returns ErrNotFound if there is no idmap of the document
+	Load(doc string) (*File, error)
+	Save(f *File) error
+	// Path returns the file the idmap of the document is stored in, empty if the storage isn't a file
+	Path(doc string) string
+}
+
+// Registry keeps the idmaps of the documents loaded from the storage, it is safe for concurrent use
+type Registry struct {
+	storage Storage
+	mu      sync.Mutex
+	files   map[string]*File
+	errs    map[string]error
+}
+
+func New(s Storage) *Registry {
+	return &Registry{storage: s, files: make(map[string]*File), errs: make(map[string]error)}
+}
+
+func (r *Registry) Storage() Storage {
+	return r.storage
+}
+
+// Get returns the idmap of the document, the idmap is loaded from the storage once
+func (r *Registry) Get(doc string) (*File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.files[doc]; ok {
+		return f, nil
+	}
+	if err, ok := r.errs[doc]; ok {
+		return nil, err
+	}
+	f, err := r.storage.Load(doc)
+	if err == nil {
+		err = f.check(doc)
+	}
+	if err != nil {
+		r.errs[doc] = err
+		return nil, err
+	}
+	r.files[doc] = f
+	return f, nil
+}
+
+// Put saves the idmap, the documents converted later in the same run get it without loading
+func (r *Registry) Put(f *File) error {
+	if f.Version == 0 {
+		f.Version = Version
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.storage.Save(f); err != nil {
+		return err
+	}
+	r.files[f.Doc] = f
+	delete(r.errs, f.Doc)
+	return nil
+}
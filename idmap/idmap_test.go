@@ -0,0 +1,124 @@
+package idmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStorages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idmap")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	cases := []struct {
+		name    string
+		storage func() Storage //new storage over the same data
+		path    string
+	}{
+		{"memory", func() Storage { return NewMemory() }, ""},
+		{"dir", func() Storage { return NewDir(dir) }, filepath.Join(dir, "user.adoc.idmap")},
+		{"json", func() Storage { return NewJSONFile(filepath.Join(dir, "idmaps.json")) }, filepath.Join(dir, "idmaps.json")},
+	}
+	for _, c := range cases {
+		s := c.storage()
+		assert.Equal(t, c.path, s.Path("user.adoc"), c.name)
+		_, err := s.Load("user.adoc")
+		assert.True(t, errors.Is(err, ErrNotFound), c.name)
+
+		f := &File{Version: Version, Doc: "user.adoc", Slug: "user", SplitLevel: 2,
+			Ids: Map{"id1": {FileName: "user_1.md", Caption: "One"}}}
+		if !assert.NoError(t, s.Save(f), c.name) {
+			continue
+		}
+		assert.NoError(t, s.Save(&File{Version: Version, Doc: "admin.adoc", Ids: Map{}}), c.name)
+		loaded, err := s.Load("user.adoc")
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, f, loaded, c.name)
+		if c.path == "" {
+			continue
+		}
+		//the files are read by the next run
+		loaded, err = c.storage().Load("user.adoc")
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, f, loaded, c.name)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idmap")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	//legacy idmap without version
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "old.adoc.idmap"), []byte("id1:\n  filename: old_1.md\n"), 0666))
+	r := New(NewDir(dir))
+	_, err = r.Get("old.adoc")
+	assert.EqualError(t, err, "idmap of old.adoc has version 0, expected 1: regenerate it")
+	_, err = r.Get("none.adoc")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	//the document is generated later in the same run
+	assert.NoError(t, r.Put(&File{Doc: "none.adoc", Ids: Map{"id": {FileName: "none_1.md"}}}))
+	f, err := r.Get("none.adoc")
+	if assert.NoError(t, err) {
+		assert.Equal(t, Version, f.Version)
+		assert.Equal(t, "none_1.md", f.Ids["id"].FileName)
+	}
+	assert.FileExists(t, filepath.Join(dir, "none.adoc.idmap"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			doc := fmt.Sprintf("doc%d.adoc", i%4)
+			if i < 4 {
+				assert.NoError(t, r.Put(&File{Doc: doc, Ids: Map{}}))
+			} else {
+				r.Get(doc)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idmap")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	doc, inc := filepath.Join(dir, "doc.adoc"), filepath.Join(dir, "inc.adoc")
+	assert.NoError(t, ioutil.WriteFile(doc, []byte("== One\n\ninclude::inc.adoc[]"), 0666))
+	assert.NoError(t, ioutil.WriteFile(inc, []byte("text"), 0666))
+	f, err := NewFile("doc.adoc", "doc", 2, []string{doc, inc}, Map{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	stale, err := f.Stale()
+	assert.NoError(t, err)
+	assert.False(t, stale)
+
+	assert.NoError(t, ioutil.WriteFile(inc, []byte("changed"), 0666))
+	stale, err = f.Stale()
+	assert.NoError(t, err)
+	assert.True(t, stale)
+
+	assert.NoError(t, os.Remove(inc))
+	stale, err = f.Stale()
+	assert.Error(t, err)
+	assert.True(t, stale)
+
+	//no sources are recorded
+	stale, err = (&File{}).Stale()
+	assert.NoError(t, err)
+	assert.False(t, stale)
+}
@@ -0,0 +1,164 @@
+package idmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Memory keeps the idmaps for the single run, e.g. for "build" command
+type Memory struct {
+	mu    sync.Mutex
+	files map[string]*File
+}
+
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]*File)}
+}
+
+func (m *Memory) Load(doc string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[doc]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", doc, ErrNotFound)
+	}
+	return f, nil
+}
+
+func (m *Memory) Save(f *File) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[f.Doc] = f
+	return nil
+}
+
+func (m *Memory) Path(doc string) string {
+	return ""
+}
+
+// Dir stores the idmaps as "<doc>.idmap" YAML files in the folder
+type Dir struct {
+	dir string
+}
+
+func NewDir(dir string) *Dir {
+	return &Dir{dir: dir}
+}
+
+func (d *Dir) Path(doc string) string {
+	return filepath.Join(d.dir, doc) + ".idmap"
+}
+
+func (d *Dir) Load(doc string) (*File, error) {
+	data, err := ioutil.ReadFile(d.Path(doc))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", d.Path(doc), ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err = yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", d.Path(doc), err)
+	}
+	return &f, nil
+}
+
+func (d *Dir) Save(f *File) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return writeFile(d.Path(f.Doc), data)
+}
+
+// writeFile writes the file if its content is changed, so the file watchers aren't triggered for nothing
+func writeFile(name string, data []byte) error {
+	if old, err := ioutil.ReadFile(name); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	return ioutil.WriteFile(name, data, 0666)
+}
+
+// jsonFile is the content of JSONFile storage
+type jsonFile struct {
+	Version int              `json:"version"`
+	Docs    map[string]*File `json:"docs"`
+}
+
+// JSONFile stores the idmaps of all the documents in a single JSON file, the file is rewritten on every Save
+type JSONFile struct {
+	name   string
+	mu     sync.Mutex
+	loaded bool
+	docs   map[string]*File
+}
+
+func NewJSONFile(name string) *JSONFile {
+	return &JSONFile{name: name}
+}
+
+func (j *JSONFile) Path(doc string) string {
+	return j.name
+}
+
+// load reads the file once, a missing file is an empty storage
+func (j *JSONFile) load() error {
+	if j.loaded {
+		return nil
+	}
+	j.docs = make(map[string]*File)
+	data, err := ioutil.ReadFile(j.name)
+	if os.IsNotExist(err) {
+		j.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var content jsonFile
+	if err = json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("%s: %w", j.name, err)
+	}
+	if content.Version != Version {
+		return fmt.Errorf("%s has version %d, expected %d: regenerate it", j.name, content.Version, Version)
+	}
+	if content.Docs != nil {
+		j.docs = content.Docs
+	}
+	j.loaded = true
+	return nil
+}
+
+func (j *JSONFile) Load(doc string) (*File, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	f, ok := j.docs[doc]
+	if !ok {
+		return nil, fmt.Errorf("%s in %s: %w", doc, j.name, ErrNotFound)
+	}
+	return f, nil
+}
+
+func (j *JSONFile) Save(f *File) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.load(); err != nil {
+		return err
+	}
+	j.docs[f.Doc] = f
+	data, err := json.MarshalIndent(jsonFile{Version, j.docs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(j.name, data)
+}
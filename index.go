@@ -0,0 +1,130 @@
+package main
+
+import (
+	"asciidoc2md/ast"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// indexEntry is an index term occurrence
+type indexEntry struct {
+	Terms   []string //primary, secondary and tertiary terms
+	File    string   //output file name
+	Id      string   //id of the nearest header
+	Caption string   //text of the nearest header
+}
+
+// indexNode is an index term with its occurrences and subterms
+type indexNode struct {
+	Term  string
+	Refs  []indexEntry
+	Nodes map[string]*indexNode
+}
+
+func (fs *FileSplitter) appendIndexEntry(t *ast.IndexTerm, h *ast.Header) {
+	e := indexEntry{Terms: t.Terms, File: fs.fileName}
+	if h != nil {
+		e.Caption = h.Title()
+		e.Id = h.Id
+		if e.Id == "" {
+			e.Id = permLink(h.Title())
+		}
+	}
+	fs.index = append(fs.index, e)
+}
+
+func (n *indexNode) add(e indexEntry, terms []string) {
+	if len(terms) == 0 {
+		for _, r := range n.Refs {
+			if r.File == e.File && r.Id == e.Id {
+				//same section is already referenced
+				return
+			}
+		}
+		n.Refs = append(n.Refs, e)
+		return
+	}
+	if n.Nodes == nil {
+		n.Nodes = make(map[string]*indexNode)
+	}
+	child, ok := n.Nodes[terms[0]]
+	if !ok {
+		child = &indexNode{Term: terms[0]}
+		n.Nodes[terms[0]] = child
+	}
+	child.add(e, terms[1:])
+}
+
+// sortedNodes returns subterms in case insensitive alphabetical order
+func (n *indexNode) sortedNodes() []*indexNode {
+	nodes := make([]*indexNode, 0, len(n.Nodes))
+	for _, node := range n.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := strings.ToLower(nodes[i].Term), strings.ToLower(nodes[j].Term)
+		if a == b {
+			return nodes[i].Term < nodes[j].Term
+		}
+		return a < b
+	})
+	return nodes
+}
+
+func (n *indexNode) write(b *strings.Builder, indent string) {
+	refs := make([]string, 0, len(n.Refs))
+	for _, r := range n.Refs {
+		url := r.File
+		if r.Id != "" {
+			url += "#" + r.Id
+		}
+		caption := r.Caption
+		if caption == "" {
+			caption = r.File
+		}
+		refs = append(refs, fmt.Sprintf("[%s](%s)", caption, url))
+	}
+	b.WriteString(indent + "* " + n.Term)
+	if len(refs) > 0 {
+		b.WriteString(": " + strings.Join(refs, ", "))
+	}
+	b.WriteString("\n")
+	for _, child := range n.sortedNodes() {
+		child.write(b, indent+"    ")
+	}
+}
+
+// indexLetter returns a letter the index term is grouped by
+func indexLetter(term string) string {
+	r, _ := utf8.DecodeRuneInString(term)
+	if !unicode.IsLetter(r) {
+		return "#"
+	}
+	return string(unicode.ToUpper(r))
+}
+
+// renderIndex renders index terms grouped by the first letter of the primary term
+func renderIndex(entries []indexEntry) string {
+	var root indexNode
+	for _, e := range entries {
+		root.add(e, e.Terms)
+	}
+	var b strings.Builder
+	b.WriteString("# Index\n")
+	letter := ""
+	for _, node := range root.sortedNodes() {
+		if l := indexLetter(node.Term); l != letter {
+			letter = l
+			b.WriteString("\n## " + letter + "\n\n")
+		}
+		node.write(&b, "")
+	}
+	return b.String()
+}
+
+func (fs *FileSplitter) writeIndexPage(name string) error {
+	return fs.writeFile(name, []byte(renderIndex(fs.index)))
+}
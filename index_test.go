@@ -0,0 +1,74 @@
+package main
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/parser"
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const indexTestInput = `
+= Doc
+
+== Header2
+
+Some ((Alpha)) text (((Beta, Gamma)))
+
+[[id3,Third header]]
+=== Header3
+
+indexterm:[beta, delta] and indexterm2:[alpha] with anchor:anchor1[Anchor text].
+
+== Header4
+
+(((Beta, Gamma)))
+`
+
+func TestSplitter_IndexTerms(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	p := parser.New(indexTestInput, nil, logger)
+	doc, err := p.Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	splitter := NewFileSplitter(doc, "slug", testConf(), ".", 2, logger)
+	splitter.init(false)
+
+	assert.Equal(t, &IdMapEntry{FileName: "slug_1.md", Caption: "Third header"}, splitter.idMaps["gotest.adoc"]["id3"])
+	assert.Equal(t, &IdMapEntry{FileName: "slug_1.md", Caption: "Anchor text"}, splitter.idMaps["gotest.adoc"]["anchor1"])
+	assert.Len(t, splitter.index, 5)
+
+	exp := `# Index
+
+## A
+
+* Alpha: [Header2](slug_1.md#header2)
+* alpha: [Header3](slug_1.md#id3)
+
+## B
+
+* Beta
+    * Gamma: [Header2](slug_1.md#header2), [Header4](slug_2.md#header4)
+* beta
+    * delta: [Header3](slug_1.md#id3)
+`
+	assert.Equal(t, exp, renderIndex(splitter.index))
+}
+
+func TestSplitter_Citations(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	input := "= Doc\n\n== Header2\n\nSee <<pp>>.\n\n[bibliography]\n== References\n\n* [[[pp,1]]] Andy Hunt\n"
+	doc, err := parser.New(input, nil, logger).Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	splitter := NewFileSplitter(doc, "slug", testConf(), ".", 2, logger)
+	splitter.init(false)
+
+	assert.Equal(t, &IdMapEntry{FileName: "slug_2.md", Caption: "[1]"}, splitter.idMaps["gotest.adoc"]["pp"])
+	link := doc.Blocks[2].(*ast.Paragraph).Blocks[1].(*ast.Link)
+	assert.Equal(t, "slug_2.md#pp", link.Url)
+	assert.Equal(t, "[1]", link.Text)
+}
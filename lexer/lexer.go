@@ -4,6 +4,7 @@ import (
 	"asciidoc2md/token"
 	"asciidoc2md/utils"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode/utf8"
 )
@@ -19,6 +20,7 @@ type Lexer struct {
 	eof bool
 	line 		 uint //current line
 	tableFlag 		bool //we've started parsing table, this flag is set after "|===" token occurred
+	lineStarts []int //byte offsets of the line beginnings, used to compute token columns
 }
 
 //lexer position
@@ -35,6 +37,13 @@ type State struct {
 func New(input string) *Lexer {
 	l := &Lexer{input: input, ch: '\n'}
 	l.line = 1
+	l.lineStarts = []int{0}
+	for i := 0; i < len(input); i++ {
+		//"\r\n" is a single line break as in readNewLine
+		if input[i] == '\n' || input[i] == '\r' && (i+1 == len(input) || input[i+1] != '\n') {
+			l.lineStarts = append(l.lineStarts, i+1)
+		}
+	}
 	l.readRune()
 	l.prevToken = &token.Token{Type: token.NEWLINE}
 	return l
@@ -178,11 +187,11 @@ func (l *Lexer) NextToken() *token.Token {
 				if tok != nil && tok.Type != token.STR {
 					l.Rewind(pos2)
 				}
-				return &token.Token{Type: token.STR, Line: l.line, Literal: l.input[pos:l.position]}
+				return l.locate(&token.Token{Type: token.STR, Line: l.line, Literal: l.input[pos:l.position]}, pos)
 			}
 
 
-			return tok
+			return l.locate(tok, pos)
 		}
 		if pos == l.position {
 			return &token.Token{Type: token.ILLEGAL, Literal: "reader got stuck", Line: l.line}
@@ -190,12 +199,42 @@ func (l *Lexer) NextToken() *token.Token {
 	}
 }
 
+// locate sets the source range of the token read from the from byte offset up to the current position
+func (l *Lexer) locate(tok *token.Token, from int) *token.Token {
+	tok.Line, tok.Col = l.lineCol(from)
+	tok.EndLine, tok.EndCol = tok.Line, tok.Col
+	if to := l.position; to > from && to <= len(l.input) {
+		_, size := utf8.DecodeLastRuneInString(l.input[from:to])
+		tok.EndLine, tok.EndCol = l.lineCol(to - size)
+	}
+	return tok
+}
+
+// lineCol returns the line and the column of the byte offset, both start from 1
+func (l *Lexer) lineCol(offset int) (uint, uint) {
+	if offset > len(l.input) {
+		offset = len(l.input)
+	}
+	i := sort.Search(len(l.lineStarts), func(i int) bool { return l.lineStarts[i] > offset }) - 1
+	return uint(i + 1), uint(utf8.RuneCountInString(l.input[l.lineStarts[i]:offset]) + 1)
+}
+
 func (l *Lexer) next() *token.Token {
 
 	switch  {
 	case l.ch == 0xFEFF && l.position == 0:
 		//BOM, skipping it
 		l.readRune()
+	case l.tableFlag && l.atWordStart() && cellSpec(l.input[l.position:]) != "":
+		//cell specifier "2+|", ".3+|", "2.2+^.^a|"
+		spec := cellSpec(l.input[l.position:])
+		for range spec {
+			l.readRune()
+		}
+		if strings.HasSuffix(spec, "a|") {
+			return l.setNewToken(token.A_COLUMN, l.line, spec)
+		}
+		return l.setNewToken(token.COLUMN, l.line, spec)
 	case l.ch == '<' && l.peekRune() == '<':
 		return l.setToken(l.readInternalLink())
 	case l.ch == '.' && l.prevToken.Type == token.NEWLINE && !utils.RuneIs(l.peekRune(), '.','*',' ','\t'):
@@ -240,6 +279,21 @@ func (l *Lexer) next() *token.Token {
 		} else {
 			return l.setNewToken(token.NL_MARK, l.line, m)
 		}
+	case l.ch == '[' && l.prevToken.Type == token.L_MARK && checkboxRE.MatchString(l.input[l.position:]):
+		//checklist item "* [x] text"
+		l.readRune() //opening bracket
+		mark := l.ch
+		l.readRune() //closing bracket
+		l.readRune()
+		l.readWhitespace() //skip whitespace after
+		return l.setNewToken(token.CHECKBOX, l.line, string(mark))
+	case l.ch == '[' && bibAnchorRE.MatchString(l.input[l.position:]):
+		//bibliography anchor "[[[ref,label]]]"
+		matches := bibAnchorRE.FindStringSubmatch(l.input[l.position:])
+		for range matches[0] {
+			l.readRune() //jump to the text after anchor
+		}
+		return l.setNewToken(token.BIB_ANCHOR, l.line, matches[1])
 	case l.ch == '[' && l.peekRune() == '[':
 		//bookmark
 		l.readRune() //second opening bracket
@@ -253,11 +307,19 @@ func (l *Lexer) next() *token.Token {
 		return l.setToken(l.readLinkName())
 
 	case l.ch == ':' && l.prevToken.Type == token.NEWLINE:
-		//ignore asciidoc options ":keyword: text"
-		l.readLine()
+		//document attribute ":keyword: text", other lines starting with colon are ignored
+		line := l.readLine()
+		if attrEntryRE.MatchString(line) {
+			return l.setNewToken(token.ATTR_ENTRY, l.line, line)
+		}
 	case l.ch == '/' && l.prevToken.Type == token.NEWLINE && l.peekRune() == '/':
+		line := l.readLine()
+		if commentBlockRE.MatchString(line) {
+			//comment block is read wholesale, without tokenizing
+			return l.setNewToken(token.COMMENT_BLOCK, l.line, l.readSyntaxBlock(strings.TrimSpace(line)))
+		}
 		//comment line
-		return l.setNewToken(token.COMMENT, l.line, l.readLine())
+		return l.setNewToken(token.COMMENT, l.line, line)
 	case l.ch == 'a' && l.tableFlag && l.peekRune() == '|':
 		l.readRune()
 		l.readRune()
@@ -290,6 +352,9 @@ func (l *Lexer) tryString() *token.Token {
 	case tok.Type == token.BLOCK_DELIM:
 		// syntax block
 		return l.setNewToken(token.SYNTAX_BLOCK, l.line, l.readSyntaxBlock(tok.Literal))
+	case tok.Type == token.PASS_DELIM:
+		//passthrough block is read as is
+		return l.setNewToken(token.PASS_BLOCK, l.line, l.readSyntaxBlock(tok.Literal))
 	case tok.Type == token.TABLE:
 		//invert flag
 		l.tableFlag = !l.tableFlag
@@ -339,7 +404,25 @@ func (l *Lexer) tryReadToken() *token.Token {
 
 }
 
-var hrefRE = regexp.MustCompile(`^((?:(?:https?:\/\/)|link:)\S+?)(?:\s|$|\[)`)
+var hrefRE = regexp.MustCompile(`^((?:(?:(?:https?|ftp|irc):\/\/)|link:|mailto:)\S+?)(?:\s|$|\[)`)
+// "<https://example.org>"
+var angleHrefRE = regexp.MustCompile(`^<((?:https?|ftp|irc):\/\/[^\s>]+)>`)
+// bare email address "join@discuss.example.org"
+var emailRE = regexp.MustCompile(`^[\w.%+-]+@[\w-]+(?:\.[\w-]+)*\.[a-zA-Z]{2,}`)
+// trailing punctuation isn't the part of the bare URL
+var urlPunctRE = regexp.MustCompile(`[.,;:!?]+$`)
+// "(((primary, secondary)))", "((term))", "indexterm:[primary]", "indexterm2:[term]"
+var indexTermRE = regexp.MustCompile(`^(?:\({3}[^()]+?\){3}|\({2}[^()]+?\){2}|indexterm2?:\[[^\]]*\])`)
+var bibAnchorRE = regexp.MustCompile(`^\[\[\[([^\s\[\],]+(?:,[^\[\]]*)?)\]\]\]`)
+var anchorRE = regexp.MustCompile(`^anchor:([^\s\[\]]+)\[([^\]]*)\]`)
+// UI macros: "kbd:[Ctrl+\]]", "btn:[Save]", "menu:File[Save As]"
+var kbdRE = regexp.MustCompile(`^kbd:\[(?:\\]|[^\]])+\]`)
+var btnRE = regexp.MustCompile(`^btn:\[[^\]]+\]`)
+var menuRE = regexp.MustCompile(`^menu:[^\s\[\]]+\[[^\]]*\]`)
+// "stem:[sqrt(4) = 2]", "latexmath:[C = \alpha + \beta Y^{\gamma}]", "asciimath:[[a,b\]]"
+var stemRE = regexp.MustCompile(`^(?:stem|latexmath|asciimath):\[(?:\\]|[^\]])*\]`)
+// table cell specifier: "colspan.rowspan+", horizontal and vertical alignment, style
+var cellSpecRE = regexp.MustCompile(`^(?:\d*(?:\.\d+)?\+)?[<^>]?(?:\.[<^>])?[adehlmsv]?\|`)
 var fencedRE = regexp.MustCompile(`^\x60{3}\s*(\S*)\s*$`)
 
 func (l *Lexer) lookupInlineKeyword(w string) (*token.Token, int) {
@@ -352,22 +435,58 @@ func (l *Lexer) lookupInlineKeyword(w string) (*token.Token, int) {
 			return &token.Token{Type: token.ILLEGAL, Literal: w, Line: l.line}, len(w)
 		}
 		return &token.Token{Type: token.INLINE_IMAGE, Line: l.line, Literal: w[:br+1]}, br + 1
+	case indexTermRE.MatchString(w):
+		lit := indexTermRE.FindString(w)
+		return &token.Token{Type: token.INDEX_TERM, Line: l.line, Literal: lit}, len(lit)
+	case kbdRE.MatchString(w):
+		lit := kbdRE.FindString(w)
+		return &token.Token{Type: token.KBD, Line: l.line, Literal: lit}, len(lit)
+	case btnRE.MatchString(w):
+		lit := btnRE.FindString(w)
+		return &token.Token{Type: token.BTN, Line: l.line, Literal: lit}, len(lit)
+	case menuRE.MatchString(w):
+		lit := menuRE.FindString(w)
+		return &token.Token{Type: token.MENU, Line: l.line, Literal: lit}, len(lit)
+	case stemRE.MatchString(w):
+		lit := stemRE.FindString(w)
+		return &token.Token{Type: token.STEM, Line: l.line, Literal: lit}, len(lit)
+	case anchorRE.MatchString(w):
+		//inline anchor "anchor:id[reftext]" is the same as "[[id,reftext]]"
+		matches := anchorRE.FindStringSubmatch(w)
+		lit := matches[1]
+		if matches[2] != "" {
+			lit += "," + matches[2]
+		}
+		return &token.Token{Type: token.BOOKMARK, Line: l.line, Literal: lit}, len(matches[0])
+	case angleHrefRE.MatchString(w):
+		matches := angleHrefRE.FindStringSubmatch(w)
+		return &token.Token{Type: token.URL, Literal: matches[1], Line: l.line}, len(matches[0])
+	case emailRE.MatchString(w):
+		lit := emailRE.FindString(w)
+		return &token.Token{Type: token.URL, Literal: "mailto:" + lit, Line: l.line}, len(lit)
 	default:
 		matches := hrefRE.FindStringSubmatch(w)
 		if len(matches) == 2 {
 			lit := matches[1]
+			if !strings.HasPrefix(w[len(lit):], "[") {
+				lit = urlPunctRE.ReplaceAllString(lit, "")
+			}
+			n := len(lit)
 			if strings.HasPrefix(lit, "link:") {
 				lit = lit[5:]
 			}
-			return &token.Token{Type: token.URL, Literal: lit, Line: l.line}, len(matches[1])
+			return &token.Token{Type: token.URL, Literal: lit, Line: l.line}, n
 		}
 	}
 	return nil, 0
 }
 
 
+var attrEntryRE = regexp.MustCompile(`^:!?\w[\w-]*!?:(?:[ \t]+.*)?$`)
+var checkboxRE = regexp.MustCompile(`^\[[ xX*]\][ \t]`)
 var admonitionRE = regexp.MustCompile(`^\s*((?:NOTE)|(?:TIP)|(?:IMPORTANT)|(?:WARNING)|(?:CAUTION)):\s(.*)$`)
 var defListRE = regexp.MustCompile(`^(.*)::\s*$`)
+var literalDelimRE = regexp.MustCompile(`^\.{4}\s*$`)
 var parConcatRE = regexp.MustCompile(`^\+\s*$`)
 var calloutRE = regexp.MustCompile(`^\s*(<(?:\.|\d+)>)\s`)
 /*
@@ -386,14 +505,24 @@ func (l *Lexer) lookupLineKeyword(w string) (*token.Token, int) {
 	//	return &token.Token{Type: token.COLUMN, Line: l.line, Literal: w}
 	case strings.HasPrefix(w, "____"): //quotation block
 		return &token.Token{Type: token.QUOTE_BLOCK, Line: l.line, Literal: "____"}, len(w)
+	case strings.HasPrefix(w, "++++"): //passthrough block delimiter
+		return &token.Token{Type: token.PASS_DELIM, Line: l.line, Literal: "++++"}, len(w)
+	case literalDelimRE.MatchString(w): //literal block delimiter
+		return &token.Token{Type: token.BLOCK_DELIM, Line: l.line, Literal: "...."}, len(w)
 	case strings.HasPrefix(w, "----"): //block delimiter
 		// actual literal could have trailing spaces, let's don't bother trimming them
 		return &token.Token{Type: token.BLOCK_DELIM, Line: l.line, Literal: "----"}, len(w)
 	case strings.HasPrefix(w, "--"): //list boundary
 		// actual literal could have trailing spaces, let's don't bother trimming them
 		return &token.Token{Type: token.L_BOUNDARY, Line: l.line, Literal: "--"}, len(w)
+	case strings.HasPrefix(w, "toc::["): //table of contents
+		return &token.Token{Type: token.TOC, Line: l.line, Literal: w}, len(w)
 	case strings.HasPrefix(w, "image::"): //block image
 		return &token.Token{Type: token.BLOCK_IMAGE, Line: l.line, Literal: w}, len(w)
+	case strings.HasPrefix(w, "video::"): //block video
+		return &token.Token{Type: token.BLOCK_VIDEO, Line: l.line, Literal: w}, len(w)
+	case strings.HasPrefix(w, "audio::"): //block audio
+		return &token.Token{Type: token.BLOCK_AUDIO, Line: l.line, Literal: w}, len(w)
 	case strings.HasPrefix(w,"****"):
 		return &token.Token{Type: token.SIDEBAR, Line: l.line, Literal: w}, len(w)
 	case w == "'''" && l.prevToken.Type == token.NEWLINE:
@@ -516,6 +645,7 @@ func (l *Lexer) readBookmark() *token.Token {
 
 }
 
+var commentBlockRE = regexp.MustCompile(`^/{4,}\s*$`)
 var exBlockRE = regexp.MustCompile(`^={4,}$`)
 
 func (l *Lexer) readHeaderOrExample() *token.Token {
@@ -601,6 +731,25 @@ func isColumn(ch rune) bool {
 	return ch == '|'
 }
 
+// cellSpec returns the table cell specifier at the beginning of s or empty string,
+// plain "|" and "a|" separators aren't specifiers
+func cellSpec(s string) string {
+	spec := cellSpecRE.FindString(s)
+	if !strings.ContainsAny(spec, "+<^>") {
+		return ""
+	}
+	return spec
+}
+
+// atWordStart checks if the current char is the first char of the line or it follows a whitespace
+func (l *Lexer) atWordStart() bool {
+	if l.position == 0 {
+		return true
+	}
+	prev := rune(l.input[l.position-1])
+	return isWhitespace(prev) || isNewLine(prev)
+}
+
 func isWhitespace(ch rune) bool {
 	return ch == ' ' || ch == '\t'
 }
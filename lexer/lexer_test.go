@@ -56,9 +56,9 @@ func TestShifts2(t *testing.T) {
 	lex := New(input)
 	//l := lex.readLine()
 	tok := lex.tryReadToken()
-	assert.Equal(t, &token.Token{token.CALLOUT_MARK,"<.>",1}, tok)
+	assert.Equal(t, &token.Token{Type: token.CALLOUT_MARK, Literal: "<.>", Line: 1}, tok)
 	tok = lex.tryReadToken()
-	assert.Equal(t, &token.Token{token.STR,"ab",1}, tok)
+	assert.Equal(t, &token.Token{Type: token.STR, Literal: "ab", Line: 1}, tok)
 }
 
 func TestNextToken(t *testing.T) {
@@ -70,6 +70,38 @@ func TestNextToken(t *testing.T) {
 	assert.Equal(t, "строка1 ===", tok.Literal)
 }
 
+func TestTokenPositions(t *testing.T) {
+	input := "= Заголовок\r\n\nтекст *жирный*\n----\ncode\nmore\n----\n"
+	l := New(input)
+	var toks []*token.Token
+	for tok := l.NextToken(); tok != nil; tok = l.NextToken() {
+		toks = append(toks, tok)
+	}
+	cases := []struct {
+		typ                        token.TokenType
+		line, col, endLine, endCol uint
+	}{
+		{token.HEADER, 1, 1, 1, 2},
+		{token.STR, 1, 3, 1, 11},
+		{token.NEWLINE, 1, 12, 1, 13},
+		{token.NEWLINE, 2, 1, 2, 1},
+		{token.STR, 3, 1, 3, 14},
+		{token.NEWLINE, 3, 15, 3, 15},
+		//syntax block starts at the opening delimiter
+		{token.SYNTAX_BLOCK, 4, 1, 7, 4},
+		{token.NEWLINE, 7, 5, 7, 5},
+		{token.EOF, 8, 1, 8, 1},
+	}
+	if !assert.Len(t, toks, len(cases)) {
+		return
+	}
+	for i, c := range cases {
+		assert.Equal(t, c.typ, toks[i].Type, "token %v", i)
+		assert.Equal(t, []uint{c.line, c.col, c.endLine, c.endCol},
+			[]uint{toks[i].Line, toks[i].Col, toks[i].EndLine, toks[i].EndCol}, "token %v: %v", i, toks[i])
+	}
+}
+
 type (
 	lexerTestCase struct {
 		name     string
@@ -273,6 +305,16 @@ some text`,
 			{token.STR, `| text7 | text8|`},	{token.EOF, ""},
 		},
 	},
+	{
+		name: "table cell specifiers",
+		input: "|===\n2+|text1 .2+^a|text2\n|1.5\n|===",
+		expected: []lt{
+			{token.TABLE, `|===`},{token.NEWLINE, "\n"},
+			{token.COLUMN, "2+|"}, {token.STR, "text1 "}, {token.A_COLUMN, ".2+^a|"}, {token.STR, "text2"},
+				{token.NEWLINE, "\n"},
+			{token.COLUMN, "|"}, {token.STR, "1.5"}, {token.NEWLINE, "\n"}, {token.TABLE, "|==="}, {token.EOF, ""},
+		},
+	},
 	{
 		name: "bookmark",
 		input: "[[bookmark1]]**Структура `json` с опциями слияния, [[bookmark2]]описание свойств, их типы и значения по умолчанию:**",
@@ -309,6 +351,68 @@ some text`,
 		},
 
 	},
+	{
+		name: "comment block",
+		input: "text1\n////\n* not a list\n== not a header\n////\ntext2",
+		expected: []lt{
+			{token.STR, "text1"}, nl,
+			{token.COMMENT_BLOCK, "* not a list\n== not a header\n"}, nl,
+			{token.STR, "text2"}, eof,
+		},
+	},
+	{
+		name: "bibliography anchors",
+		input: "* [[[pp]]] Andy Hunt\n* [[[gof,GoF]]] Erich Gamma",
+		expected: []lt{
+			{token.L_MARK, "*"}, {token.BIB_ANCHOR, "pp"}, {token.STR, " Andy Hunt"}, nl,
+			{token.L_MARK, "*"}, {token.BIB_ANCHOR, "gof,GoF"}, {token.STR, " Erich Gamma"}, eof,
+		},
+	},
+	{
+		name: "stem",
+		input: "[stem]\n++++\nsqrt(4) = 2\n++++\nis stem:[x^2] and latexmath:[[a, b\\]]",
+		expected: []lt{
+			{token.BLOCK_OPTS, "stem"}, nl,
+			{token.PASS_BLOCK, "sqrt(4) = 2\n"}, nl,
+			{token.STR, "is "}, {token.STEM, "stem:[x^2]"}, {token.STR, " and "},
+			{token.STEM, `latexmath:[[a, b\]]`}, eof,
+		},
+	},
+	{
+		name: "literal block",
+		input: "....\n* not a list\n....\ntext",
+		expected: []lt{
+			{token.SYNTAX_BLOCK, "* not a list\n"}, nl,
+			{token.STR, "text"}, eof,
+		},
+	},
+	{
+		name: "media macros",
+		input: "video::intro.mp4[width=640]\naudio::sound.mp3[]",
+		expected: []lt{
+			{token.BLOCK_VIDEO, "video::intro.mp4[width=640]"}, nl,
+			{token.BLOCK_AUDIO, "audio::sound.mp3[]"}, eof,
+		},
+	},
+	{
+		name: "url forms",
+		input: "see <https://a.org>, ftp://b.org. mailto:c@d.org[Mail] e@f.org ++https://g.org++",
+		expected: []lt{
+			{token.STR, "see "}, {token.URL, "https://a.org"}, {token.STR, ", "},
+			{token.URL, "ftp://b.org"}, {token.STR, ". "},
+			{token.URL, "mailto:c@d.org"}, {token.LINK_NAME, "Mail"}, {token.STR, " "},
+			{token.URL, "mailto:e@f.org"}, {token.STR, " ++https://g.org++"}, eof,
+		},
+	},
+	{
+		name: "toc macro",
+		input: "text1\ntoc::[]\ntext2",
+		expected: []lt{
+			{token.STR, "text1"}, nl,
+			{token.TOC, "toc::[]"}, nl,
+			{token.STR, "text2"}, eof,
+		},
+	},
 	{
 		name: "definition list",
 		input:
@@ -351,6 +455,48 @@ text 2`,
 			{token.CALLOUT_MARK, "<12>"},{token.STR, "text12"}, eof,
 		},
 	},
+	{
+		name: "checklist",
+		input: `* [x] done
+* [ ] todo
+* [*] also done
+* [link] text`,
+		expected: []lt{
+			{token.L_MARK, "*"}, {token.CHECKBOX, "x"}, {token.STR, "done"}, nl,
+			{token.L_MARK, "*"}, {token.CHECKBOX, " "}, {token.STR, "todo"}, nl,
+			{token.L_MARK, "*"}, {token.CHECKBOX, "*"}, {token.STR, "also done"}, nl,
+			{token.L_MARK, "*"}, {token.STR, "[link] text"}, eof,
+		},
+	},
+	{
+		name: "attribute entries",
+		input: `:hardbreaks-option:
+:toclevels: 2
+:!sectnums:
+text`,
+		expected: []lt{
+			{token.ATTR_ENTRY, ":hardbreaks-option:"}, nl,
+			{token.ATTR_ENTRY, ":toclevels: 2"}, nl,
+			{token.ATTR_ENTRY, ":!sectnums:"}, nl,
+			{token.STR, "text"}, eof,
+		},
+	},
+	{
+		name: "ui macros",
+		input: `Press kbd:[Ctrl+\]] or btn:[Save] in menu:File[Save As > Copy].`,
+		expected: []lt{
+			{token.STR, "Press "}, {token.KBD, "kbd:[Ctrl+\\]]"}, {token.STR, " or "}, {token.BTN, "btn:[Save]"},
+			{token.STR, " in "}, {token.MENU, "menu:File[Save As > Copy]"}, {token.STR, "."}, eof,
+		},
+	},
+	{
+		name: "index terms and anchors",
+		input: `a ((term)) b (((x, y))) indexterm:[z] anchor:id1[Ref text]`,
+		expected: []lt{
+			{token.STR, "a "}, {token.INDEX_TERM, "((term))"}, {token.STR, " b "}, {token.INDEX_TERM, "(((x, y)))"},
+			{token.STR, " "}, {token.INDEX_TERM, "indexterm:[z]"}, {token.STR, " "}, {token.BOOKMARK, "id1,Ref text"}, eof,
+		},
+	},
 
 }
 
@@ -0,0 +1,198 @@
+// Package lint reports known problem patterns of the asciidoc sources: parse errors, unclosed delimiters,
+// duplicate ids, malformed markup and so on. Some problems have safe autofixes.
+package lint
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/diag"
+	"asciidoc2md/parser"
+	"cdr.dev/slog"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Rule ids
+const (
+	RuleParseError         = "parse-error"
+	RuleIllegalToken       = "illegal-token"
+	RuleUnclosedDelimiter  = "unclosed-delimiter"
+	RuleDuplicateId        = "duplicate-id"
+	RuleHeaderLevel        = "header-level"
+	RuleUnbalancedMarkup   = "unbalanced-markup"
+	RuleTableCells         = "table-cells"
+	RuleBulletMarker       = "bullet-marker"
+	RuleNestedXref         = "nested-xref"
+	RuleMultilineMonospace = "multiline-monospace"
+)
+
+type Rule struct {
+	Id          string
+	Severity    diag.Severity
+	Description string
+}
+
+// Rules lists all the rules with their default severities
+var Rules = []Rule{
+	{RuleParseError, diag.Error, "the parser failed, the source is written as is"},
+	{RuleIllegalToken, diag.Error, "the lexer can't read the source, e.g. unclosed \"<<\" cross reference"},
+	{RuleUnclosedDelimiter, diag.Error, "the block delimiter has no closing delimiter"},
+	{RuleDuplicateId, diag.Error, "the id of the header or the anchor is already defined"},
+	{RuleHeaderLevel, diag.Warning, "the header level is more than one level deeper than the previous header"},
+	{RuleUnbalancedMarkup, diag.Warning, "the paragraph has unpaired \"`\", \"**\" or \"__\" marks"},
+	{RuleTableCells, diag.Warning, "the table cell count isn't a multiple of the column count"},
+	{RuleBulletMarker, diag.Warning, "\"•\" is used as the list marker instead of \"*\" (fixable)"},
+	{RuleNestedXref, diag.Error, "cross reference \"<<\" is opened inside another one (fixable)"},
+	{RuleMultilineMonospace, diag.Warning, "monospace text spans several lines (fixable)"},
+}
+
+// Fix is the replacement of the source lines from Line to EndLine
+type Fix struct {
+	Line    uint
+	EndLine uint
+	Text    string
+}
+
+// Issue is the problem found by the rule
+type Issue struct {
+	Rule     string
+	Severity diag.Severity
+	Pos      ast.Pos
+	Message  string
+	Fix      *Fix //safe autofix, nil if the issue isn't fixable
+}
+
+func (i *Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", i.Pos.Location(), i.Severity, i.Message, i.Rule)
+}
+
+type Linter struct {
+	read     parser.IncludeFunc
+	log      slog.Logger
+	disabled map[string]bool
+	issues   []*Issue
+}
+
+// New creates the linter, read returns the content of the document and the included files by their names
+func New(read parser.IncludeFunc, log slog.Logger) *Linter {
+	return &Linter{read: read, log: log, disabled: make(map[string]bool)}
+}
+
+// Disable turns the rules off
+func (l *Linter) Disable(rules ...string) error {
+	for _, id := range rules {
+		if severity(id) < 0 {
+			return fmt.Errorf("unknown lint rule: %s", id)
+		}
+		l.disabled[id] = true
+	}
+	return nil
+}
+
+func severity(rule string) diag.Severity {
+	for _, r := range Rules {
+		if r.Id == rule {
+			return r.Severity
+		}
+	}
+	return -1
+}
+
+func (l *Linter) report(rule string, pos ast.Pos, fix *Fix, format string, args ...interface{}) {
+	if l.disabled[rule] {
+		return
+	}
+	l.issues = append(l.issues, &Issue{Rule: rule, Severity: severity(rule), Pos: pos, Message: fmt.Sprintf(format, args...), Fix: fix})
+}
+
+// Lint checks the document and the included files, the issues are sorted by file and position
+func (l *Linter) Lint(name string) ([]*Issue, error) {
+	l.issues = nil
+	data, err := l.read(name)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{name}
+	sources := map[string][]byte{name: data}
+	p := parser.New(string(data), func(inc string) ([]byte, error) {
+		data, err := l.read(inc)
+		if err == nil && sources[inc] == nil {
+			files = append(files, inc)
+			sources[inc] = data
+		}
+		return data, err
+	}, l.log)
+	var diags diag.Collector
+	p.SetDiagnostics(&diags)
+	doc, err := p.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range diags.Diagnostics() {
+		l.report(RuleParseError, d.Pos, nil, "%v", d.Err)
+	}
+	for _, file := range files {
+		l.log.Debug(context.Background(), "linting file", slog.F("file", file))
+		l.lintSource(file, string(sources[file]))
+		l.lintTokens(file, string(sources[file]))
+	}
+	l.lintDocument(doc)
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i].Pos, l.issues[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return l.issues, nil
+}
+
+// Print writes the issues grouped by file
+func Print(w io.Writer, issues []*Issue) {
+	var errs, warns int
+	file := "\x00"
+	for _, i := range issues {
+		if i.Pos.File != file {
+			file = i.Pos.File
+			fmt.Fprintf(w, "%s:\n", file)
+		}
+		pos := i.Pos
+		pos.File = ""
+		fmt.Fprintf(w, "  %s: %s: %s [%s]\n", pos.Location(), i.Severity, i.Message, i.Rule)
+		if i.Severity == diag.Error {
+			errs++
+		} else {
+			warns++
+		}
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", errs, warns)
+}
+
+type jsonIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     uint   `json:"line"`
+	Col      uint   `json:"col,omitempty"`
+	EndLine  uint   `json:"endLine,omitempty"`
+	EndCol   uint   `json:"endCol,omitempty"`
+	Message  string `json:"message"`
+	Fixable  bool   `json:"fixable"`
+}
+
+// WriteJSON writes the issues as JSON array
+func WriteJSON(w io.Writer, issues []*Issue) error {
+	res := make([]jsonIssue, 0, len(issues))
+	for _, i := range issues {
+		res = append(res, jsonIssue{i.Rule, i.Severity.String(), i.Pos.File, i.Pos.Line, i.Pos.Col,
+			i.Pos.EndLine, i.Pos.EndCol, i.Message, i.Fix != nil})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
@@ -0,0 +1,122 @@
+package lint
+
+import (
+	"asciidoc2md/diag"
+	"bytes"
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogtest"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func files(m map[string]string) func(name string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		s, ok := m[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(s), nil
+	}
+}
+
+// issues as "rule@line:col"
+func brief(issues []*Issue) []string {
+	res := []string{}
+	for _, i := range issues {
+		res = append(res, fmt.Sprintf("%s@%d:%d", i.Rule, i.Pos.Line, i.Pos.Col))
+	}
+	return res
+}
+
+var cases = []struct {
+	name  string
+	input string
+	exp   []string
+}{
+	{"clean", "== One\n\ntext `code`\n\n=== Two\n\n----\n`\n----", []string{}},
+	{"bullets", "list:\n\n• one\n  •  two", []string{"bullet-marker@3:1", "bullet-marker@4:3"}},
+	{"nested xref", "see <<аналогично <<PlholderF,здесь>>", []string{"nested-xref@1:5"}},
+	{"multiline monospace", "call `Method(\n  null)` now", []string{"unbalanced-markup@1:1", "multiline-monospace@1:6"}},
+	{"monospace closed by the next span line", "Use `foo\nbar` here and\nthen `baz", []string{"unbalanced-markup@1:1", "multiline-monospace@1:5"}},
+	{"unbalanced", "call `Method( and **bold", []string{"unbalanced-markup@1:1", "unbalanced-markup@1:1"}},
+	{"unclosed", "====\ntext\n\n----\ncode", []string{"unclosed-delimiter@1:1", "unclosed-delimiter@4:1"}},
+	{"duplicate id", "[[one]]\n== One\n\n[#one]\n== Two", []string{"duplicate-id@5:1"}},
+	{"header level", "== One\n\n==== Three\n\n=== Two", []string{"header-level@3:1"}},
+	{"table cells", "[cols=\"1,1\"]\n|===\n|a |b\n|c\n|===", []string{"table-cells@1:1"}},
+	{"table spans", "[cols=\"1,1\"]\n|===\n2+|a\n|b |c\n|===", []string{}},
+}
+
+func TestLint(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	for _, c := range cases {
+		issues, err := New(files(map[string]string{"test.adoc": c.input}), logger).Lint("test.adoc")
+		if !assert.NoError(t, err, c.name) {
+			continue
+		}
+		assert.Equal(t, c.exp, brief(issues), c.name)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	l := New(files(map[string]string{
+		"main.adoc": "== One\n\ninclude::part.adoc[]",
+		"part.adoc": "[[one]]\n== Part\n\n• item",
+	}), logger)
+	assert.NoError(t, l.Disable(RuleDuplicateId))
+	assert.Error(t, l.Disable("no-such-rule"))
+	issues, err := l.Lint("main.adoc")
+	if !assert.NoError(t, err) || !assert.Len(t, issues, 1) {
+		return
+	}
+	assert.Equal(t, "part.adoc:4:1: warning: \"•\" list marker, use \"*\" [bullet-marker]", issues[0].String())
+	assert.Equal(t, diag.Warning, issues[0].Severity)
+}
+
+func TestApply(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	src := "• one\r\n• see <<text <<id>>\r\ncall `Method(\r\n  null)`\r\n"
+	issues, err := New(files(map[string]string{"test.adoc": src}), logger).Lint("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	fixed, n := Apply([]byte(src), issues)
+	//the second fix of the line is left for the next run
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "* one\r\n• see text <<id>>\r\ncall `Method( null)`\r\n", string(fixed))
+
+	issues, err = New(files(map[string]string{"test.adoc": string(fixed)}), logger).Lint("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	fixed, n = Apply(fixed, issues)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "* one\r\n* see text <<id>>\r\ncall `Method( null)`\r\n", string(fixed))
+
+	//the line closing the span doesn't open another one
+	src = "text\nUse `foo\nbar` here and\nthen `baz\n"
+	issues, err = New(files(map[string]string{"test.adoc": src}), logger).Lint("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	fixed, n = Apply([]byte(src), issues)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "text\nUse `foo bar` here and\nthen `baz\n", string(fixed))
+}
+
+func TestWriteJSON(t *testing.T) {
+	var w bytes.Buffer
+	issues := []*Issue{{Rule: RuleBulletMarker, Severity: diag.Warning, Message: "msg", Fix: &Fix{1, 1, "* a"}}}
+	issues[0].Pos.File = "a.adoc"
+	issues[0].Pos.Line = 1
+	issues[0].Pos.Col = 3
+	assert.NoError(t, WriteJSON(&w, issues))
+	assert.True(t, strings.Contains(w.String(), `"severity": "warning"`), w.String())
+	assert.True(t, strings.Contains(w.String(), `"fixable": true`), w.String())
+	w.Reset()
+	Print(&w, issues)
+	assert.Equal(t, "a.adoc:\n  1:3: warning: msg [bullet-marker]\n0 error(s), 1 warning(s)\n", w.String())
+}
@@ -0,0 +1,238 @@
+package lint
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/lexer"
+	"asciidoc2md/token"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// verbatim blocks, the content isn't checked
+var verbatimDelimRE = regexp.MustCompile(`^(?:-{4,}|\.{4,}|\+{4,}|/{4,}|\x60{3}.*)$`)
+
+// compound blocks: example, sidebar, quote, open block, table
+var compoundRE = regexp.MustCompile(`^(?:={4,}|\*{4,}|_{4,}|--|\|={3,})$`)
+
+var bulletRE = regexp.MustCompile(`^(\s*)•\s+`)
+
+// "<<text <<id,caption>>" and "<<<<id>>"
+var nestedXrefRE = regexp.MustCompile(`<<([^<>]*)<<`)
+
+type delimiter struct {
+	literal string
+	line    uint
+}
+
+func isDelimiter(line string) bool {
+	return verbatimDelimRE.MatchString(line) || compoundRE.MatchString(line)
+}
+
+// lintSource checks the source lines: delimiters, list markers and so on
+func (l *Linter) lintSource(file, src string) {
+	lines := strings.Split(src, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	var stack []delimiter
+	var verbatim *delimiter
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRightFunc(lines[i], isSpace)
+		n := uint(i + 1)
+		if verbatim != nil {
+			if line == verbatim.literal || strings.HasPrefix(verbatim.literal, "```") && line == "```" {
+				verbatim = nil
+			}
+			continue
+		}
+		switch {
+		case verbatimDelimRE.MatchString(line):
+			verbatim = &delimiter{line, n}
+			continue
+		case compoundRE.MatchString(line):
+			if len(stack) > 0 && stack[len(stack)-1].literal == line {
+				stack = stack[:len(stack)-1]
+			} else {
+				stack = append(stack, delimiter{line, n})
+			}
+			continue
+		case strings.HasPrefix(line, "//"):
+			continue
+		}
+		if m := bulletRE.FindStringSubmatch(line); m != nil {
+			l.report(RuleBulletMarker, linePos(file, n, m[1]), &Fix{n, n, bulletRE.ReplaceAllString(lines[i], "$1* ")},
+				"\"•\" list marker, use \"*\"")
+		}
+		if loc := nestedXrefRE.FindStringIndex(line); loc != nil {
+			l.report(RuleNestedXref, linePos(file, n, line[:loc[0]]), &Fix{n, n, nestedXrefRE.ReplaceAllString(lines[i], "$1<<")},
+				"cross reference is opened inside another one")
+		}
+		if strings.Count(line, "`")%2 == 1 {
+			//the closing line of the span isn't an opening one
+			i = l.lintMonospace(file, lines, i)
+		}
+	}
+	if verbatim != nil {
+		stack = append(stack, *verbatim)
+	}
+	for _, d := range stack {
+		l.report(RuleUnclosedDelimiter, ast.Pos{File: file, Line: d.line, Col: 1}, nil,
+			"block delimiter %q is not closed", d.literal)
+	}
+}
+
+// lintMonospace looks for the closing backtick on the next lines of the paragraph,
+// it returns the last line of the monospace span or from if the span isn't closed
+func (l *Linter) lintMonospace(file string, lines []string, from int) int {
+	count := strings.Count(lines[from], "`")
+	for i := from + 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || isDelimiter(line) {
+			//unbalanced-markup reports the paragraph
+			return from
+		}
+		count += strings.Count(line, "`")
+		if count%2 == 1 {
+			continue
+		}
+		joined := strings.TrimRightFunc(lines[from], isSpace)
+		for _, next := range lines[from+1 : i+1] {
+			joined += " " + strings.TrimSpace(next)
+		}
+		start := lines[from][:strings.Index(lines[from], "`")]
+		pos := linePos(file, uint(from+1), start)
+		pos.EndLine = uint(i + 1)
+		l.report(RuleMultilineMonospace, pos, &Fix{uint(from + 1), uint(i + 1), joined},
+			"monospace text spans %d lines", i-from+1)
+		return i
+	}
+	return from
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+func linePos(file string, line uint, before string) ast.Pos {
+	return ast.Pos{File: file, Line: line, Col: uint(utf8.RuneCountInString(before)) + 1}
+}
+
+// maxTokens stops the lexer if it got stuck
+const maxTokens = 1 << 20
+
+// lintTokens reports the tokens the lexer can't read
+func (l *Linter) lintTokens(file, src string) {
+	lex := lexer.New(src)
+	for i := 0; i < maxTokens; i++ {
+		tok := lex.NextToken()
+		if tok.Type == token.EOF {
+			return
+		}
+		if tok.Type == token.ILLEGAL {
+			l.report(RuleIllegalToken, ast.Pos{File: file, Line: tok.Line, Col: tok.Col, EndLine: tok.EndLine, EndCol: tok.EndCol}, nil,
+				"cannot read %q", tok.Literal)
+		}
+	}
+}
+
+// lintDocument checks the parsed document
+func (l *Linter) lintDocument(doc *ast.Document) {
+	ids := make(map[string]ast.Pos)
+	checkId := func(id string, pos ast.Pos) {
+		if id == "" {
+			return
+		}
+		if first, ok := ids[id]; ok {
+			l.report(RuleDuplicateId, pos, nil, "duplicate id %q, first defined at %s", id, first.Location())
+			return
+		}
+		ids[id] = pos
+	}
+	prevLevel := 0
+	doc.Walk(func(b ast.Block, root *ast.Document) bool {
+		switch b := b.(type) {
+		case *ast.Header:
+			checkId(b.Id, b.Pos)
+			if b.Float {
+				break
+			}
+			if prevLevel > 0 && b.Level > prevLevel+1 {
+				l.report(RuleHeaderLevel, b.Pos, nil, "header level %d follows level %d", b.Level, prevLevel)
+			}
+			prevLevel = b.Level
+		case *ast.Bookmark:
+			checkId(b.Literal, b.Pos)
+		case *ast.BibAnchor:
+			checkId(b.Id, b.Pos)
+		case *ast.Paragraph:
+			l.lintMarkup(b)
+		case *ast.Table:
+			l.lintTable(b)
+		}
+		return true
+	}, doc)
+}
+
+var markupMarks = []string{"`", "**", "__"}
+
+func (l *Linter) lintMarkup(p *ast.Paragraph) {
+	var text strings.Builder
+	for _, b := range p.Blocks {
+		if t, ok := b.(*ast.Text); ok {
+			text.WriteString(t.Text)
+		}
+	}
+	s := text.String()
+	for _, mark := range markupMarks {
+		if strings.Count(s, mark)%2 == 1 {
+			l.report(RuleUnbalancedMarkup, p.Pos, nil, "unpaired %q", mark)
+		}
+	}
+}
+
+func (l *Linter) lintTable(t *ast.Table) {
+	if t.Columns == 0 {
+		return
+	}
+	count := 0
+	for i := range t.Cells {
+		if span, ok := t.Spans[i]; ok {
+			count += span.Cols * span.Rows
+		} else {
+			count++
+		}
+	}
+	if count%t.Columns != 0 {
+		l.report(RuleTableCells, t.Pos, nil, "table has %d cells, not a multiple of %d columns", count, t.Columns)
+	}
+}
+
+// Apply applies the fixes of the issues to the source, returns the fixed source and the number of the applied fixes.
+// Overlapping fixes are skipped, they are left for the next run.
+func Apply(src []byte, issues []*Issue) ([]byte, int) {
+	var fixes []*Fix
+	for _, i := range issues {
+		if i.Fix != nil {
+			fixes = append(fixes, i.Fix)
+		}
+	}
+	lines := strings.Split(string(src), "\n")
+	applied := 0
+	//from the end, so the line numbers of the remaining fixes stay valid
+	last := uint(len(lines) + 1)
+	for k := len(fixes) - 1; k >= 0; k-- {
+		f := fixes[k]
+		if f.EndLine >= last || f.Line == 0 || int(f.EndLine) > len(lines) {
+			continue
+		}
+		text := f.Text
+		if strings.HasSuffix(lines[f.Line-1], "\r") {
+			text = strings.Replace(text, "\n", "\r\n", -1) + "\r"
+		}
+		lines = append(lines[:f.Line-1], append([]string{text}, lines[f.EndLine:]...)...)
+		last = f.Line
+		applied++
+	}
+	return []byte(strings.Join(lines, "\n")), applied
+}
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"asciidoc2md/ast"
+	"asciidoc2md/diag"
+	"asciidoc2md/html"
+	"asciidoc2md/idmap"
+	"asciidoc2md/lint"
+	"asciidoc2md/markdown"
 	"asciidoc2md/parser"
 	"asciidoc2md/settings"
 	"cdr.dev/slog"
@@ -8,24 +14,34 @@ import (
 	"context"
 	"github.com/alecthomas/kong"
 	"github.com/fatih/color"
+	"io"
 	"io/ioutil"
 	stdLog "log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 var log slog.Logger //global logger
 
 func initLog(verbose bool) {
 	//os.Setenv("FORCE_COLOR", "TRUE")
+	log = makeLog(color.Output, verbose)
 	if verbose {
-		log = sloghuman.Make(color.Output).Leveled(slog.LevelDebug)
 		return
 	}
-	log = sloghuman.Make(color.Output)
 	stdLog.SetOutput(slog.Stdlib(context.Background(), log).Writer())
 }
 
+// makeLog creates the logger writing to w, e.g. to the buffer of a parallel job
+func makeLog(w io.Writer, verbose bool) slog.Logger {
+	if verbose {
+		return sloghuman.Make(w).Leveled(slog.LevelDebug)
+	}
+	return sloghuman.Make(w)
+}
+
 
 
 type CLI struct {
@@ -35,15 +51,47 @@ type CLI struct {
 	SplitLevel   int    `optional help:"A level of the headers to split a file at." default:2`
 	Dump         string `help:"Write parsed document to file."`
 	ArtifactsDir string `optional name:"art" type:"existingdir" default:"." help:"Artifacts folder where asciidoc2md looks for .idmap files."`
+	IdMaps       string `name:"idmaps" help:"Idmap storage: dir (<doc>.idmap YAML files in the artifacts folder), json (single idmaps.json file in the artifacts folder) or memory (build command only)." enum:"dir,json,memory" default:"dir"`
+	MaxErrors    int    `help:"Number of the parse errors tolerated, the command fails if there are more errors. Overrides max_errors config option." default:"-1"`
 	GenMap       struct {
 		Input string `arg help:"*.adoc file to process." type:"existingfile" name:"file.adoc"`
 		WriteNav string `optional help:"Path to mkdocs.yml file to write navigation index." type:"existingfile"`
 	} `cmd:"" help:"Generate <file.adoc.idmap> file."`
 	Convert struct {
+		Input string `arg help:"*.adoc file or *.json AST exported by export-ast to process." type:"existingfile" name:"file.adoc"`
+		Out string `help:"Output directory." short:"o" type:"existingdir"`
+		ImagePath string `help:"A relative path to the images folder." short:"im" default:"images/" `
+		Flavor string `help:"Markdown flavor: mkdocs, gfm, commonmark, hugo or docusaurus. Overrides markdown.flavor config option."`
+		Format string `help:"Output format: md or html." enum:"md,html" default:"md"`
+		Template string `help:"Go html/template page layout for html output." type:"existingfile"`
+		Single bool `help:"Write html output into a single <slug>.html file instead of splitting it."`
+	} `cmd:"" help:"Convert <file.adoc> into markdown or html."`
+	ExportAst struct {
 		Input string `arg help:"*.adoc file to process." type:"existingfile" name:"file.adoc"`
+		Out string `help:"Output JSON file, the AST is written to stdout if it isn't set." short:"o"`
+	} `cmd:"" help:"Export parsed <file.adoc> as JSON AST, see ast/ast.schema.json for the format."`
+	Lint struct {
+		Input string `arg help:"*.adoc file to check." type:"existingfile" name:"file.adoc"`
+		Fix bool `help:"Apply safe autofixes to the source files."`
+		Format string `help:"Output format: text or json." enum:"text,json" default:"text"`
+		Disable []string `help:"Comma separated rule ids to turn off."`
+	} `cmd:"" help:"Check <file.adoc> and included files for known problems, the command fails if there are errors."`
+	Watch struct {
+		Input string `arg help:"*.adoc file to convert on changes." type:"existingfile" name:"file.adoc"`
 		Out string `help:"Output directory." short:"o" type:"existingdir"`
 		ImagePath string `help:"A relative path to the images folder." short:"im" default:"images/" `
-	} `cmd:"" help:"Convert <file.adoc> into markdown."`
+		Flavor string `help:"Markdown flavor: mkdocs, gfm, commonmark, hugo or docusaurus. Overrides markdown.flavor config option."`
+		Format string `help:"Output format: md or html." enum:"md,html" default:"md"`
+		Template string `help:"Go html/template page layout for html output." type:"existingfile"`
+		Single bool `help:"Write html output into a single <slug>.html file instead of splitting it."`
+		WriteNav string `optional help:"Path to mkdocs.yml file to write navigation index." type:"existingfile"`
+		Poll bool `help:"Poll the files instead of using inotify."`
+		Interval time.Duration `help:"Polling interval." default:"1s"`
+	} `cmd:"" help:"Convert <file.adoc> and its idmap, then convert it again when the document, included files, config or idmaps of the linked documents change."`
+	Build struct {
+		Guides []string `arg optional help:"Names of the guides to build, all the project guides are built by default." name:"guide"`
+		Jobs int `help:"Number of the guides processed in parallel, the number of CPUs by default." short:"j"`
+	} `cmd:"" help:"Build idmaps of the project guides listed in the config and then convert them."`
 }
 var cli CLI
 
@@ -64,27 +112,52 @@ func main() {
 	} else {
 		initLog(false)
 	}
+	if cli.IdMaps == "memory" && !strings.HasPrefix(ctx.Command(), "build") {
+		//the idmaps are lost when the command exits, the cross links of the other runs aren't resolved
+		log.Fatal(context.Background(), "invalid option: memory idmaps are supported by build command only", slog.F("idmaps", cli.IdMaps))
+	}
 	switch ctx.Command() {
 	case "gen-map <file.adoc>":
 		genIdMap()
 
 	case "convert <file.adoc>":
 		convert()
+
+	case "export-ast <file.adoc>":
+		exportAst()
+
+	case "lint <file.adoc>":
+		lintFile()
+
+	case "watch <file.adoc>":
+		watchFile()
+
+	case "build", "build <guide>":
+		buildProject()
 	}
 
 }
 
 func initConfigCLI(configFile string, opts *CLI) *settings.Config {
+	config, err := loadConfigCLI(configFile, opts)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+// loadConfigCLI reads the configuration file and applies the command line options
+func loadConfigCLI(configFile string, opts *CLI) (*settings.Config, error) {
 	var config *settings.Config
 	if configFile != "" {
 
 		str, err := ioutil.ReadFile(configFile)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		config, err = settings.Parse(str)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 	} else {
 		config = &settings.Config{}
@@ -95,9 +168,27 @@ func initConfigCLI(configFile string, opts *CLI) *settings.Config {
 		if opts.GenMap.Input != "" {
 			config.InputFile = opts.GenMap.Input
 		}
+		if opts.Watch.Input != "" {
+			config.InputFile = opts.Watch.Input
+		}
 		config.NavFile = opts.GenMap.WriteNav
+		if opts.Watch.WriteNav != "" {
+			config.NavFile = opts.Watch.WriteNav
+		}
+		if opts.Convert.Flavor != "" {
+			config.Markdown.Flavor = opts.Convert.Flavor
+		}
+		if opts.Watch.Flavor != "" {
+			config.Markdown.Flavor = opts.Watch.Flavor
+		}
+		if opts.MaxErrors >= 0 {
+			config.MaxErrors = opts.MaxErrors
+		}
 	}
-	return config
+	if _, err := markdown.FlavorByName(config.Markdown.Flavor); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
 
 func genIdMap() {
@@ -123,27 +214,159 @@ func initSplitter(inputFile string, imagePath string, outPath string, slug strin
 	log.Info(ctx, "input file", slog.F("name", inputFile))
 	log.Info(ctx, "image path", slog.F("path", imagePath))
 
-	input, err := ioutil.ReadFile(inputFile)
+	var diags diag.Collector
+	doc, includes, err := parseFileDeps(inputFile, log, &diags)
 	if err != nil {
 		panic(err)
 	}
+	checkDiagnostics(&diags, conf.MaxErrors)
+	if dumpFile != "" {
+		err = ioutil.WriteFile(dumpFile, []byte(doc.String()), os.ModePerm)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	splitter := NewFileSplitter(doc, slug, conf, outPath, splitLvl, log)
+	splitter.SetIdMaps(newIdMapRegistry(conf.ArtifactsDir))
+	splitter.SetSources(append([]string{inputFile}, includes...))
+	return splitter
+}
 
+// newIdMapRegistry creates the registry with the idmap storage set by --idmaps option
+func newIdMapRegistry(artifactsDir string) *idmap.Registry {
+	switch cli.IdMaps {
+	case "json":
+		return idmap.New(idmap.NewJSONFile(filepath.Join(artifactsDir, "idmaps.json")))
+	case "memory":
+		return idmap.New(idmap.NewMemory())
+	}
+	return idmap.New(idmap.NewDir(artifactsDir))
+}
+
+// parseFile parses the asciidoc file, "*.json" files are loaded as the AST written by export-ast.
+// Parse errors are recorded by diags and parsing continues.
+func parseFile(inputFile string, log slog.Logger, diags *diag.Collector) (*ast.Document, error) {
+	doc, _, err := parseFileDeps(inputFile, log, diags)
+	return doc, err
+}
+
+// parseFileDeps parses the file as parseFile does and returns the paths of the included files,
+// the files which cannot be read are listed too
+func parseFileDeps(inputFile string, log slog.Logger, diags *diag.Collector) (*ast.Document, []string, error) {
+	input, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.EqualFold(filepath.Ext(inputFile), ".json") {
+		doc, err := ast.FromJSON(input)
+		return doc, nil, err
+	}
+	var deps []string
 	dir, name := filepath.Split(inputFile)
 	p := parser.New(string(input), func(name string) ([]byte, error) {
+		deps = append(deps, filepath.Join(dir, name))
 		return ioutil.ReadFile(filepath.Join(dir, name))
 	}, log)
+	p.SetDiagnostics(diags)
 	doc, err := p.Parse(name)
+	return doc, deps, err
+}
+
+// checkDiagnostics prints the diagnostics grouped by file and exits if there are more than maxErrors errors
+func checkDiagnostics(diags *diag.Collector, maxErrors int) {
+	diags.Print(os.Stderr)
+	if n := diags.Count(diag.Error); n > maxErrors {
+		log.Fatal(context.Background(), "too many parse errors", slog.F("errors", n), slog.F("max", maxErrors))
+	}
+}
+
+func exportAst() {
+	var diags diag.Collector
+	doc, err := parseFile(cli.ExportAst.Input, log, &diags)
 	if err != nil {
 		panic(err)
 	}
-	if dumpFile != "" {
-		err = ioutil.WriteFile(dumpFile, []byte(doc.String()), os.ModePerm)
+	checkDiagnostics(&diags, initConfigCLI(cli.Config, &cli).MaxErrors)
+	data, err := ast.ToJSON(doc)
+	if err != nil {
+		panic(err)
+	}
+	if cli.ExportAst.Out == "" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+	} else {
+		err = ioutil.WriteFile(cli.ExportAst.Out, data, os.ModePerm)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func lintFile() {
+	dir, name := filepath.Split(cli.Lint.Input)
+	read := func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(dir, name))
+	}
+	l := lint.New(read, log)
+	if err := l.Disable(cli.Lint.Disable...); err != nil {
+		log.Fatal(context.Background(), "invalid option", slog.Error(err))
+	}
+	issues, err := l.Lint(name)
+	if err != nil {
+		log.Fatal(context.Background(), "cannot lint file", slog.F("file", cli.Lint.Input), slog.Error(err))
+	}
+	if cli.Lint.Fix && fixFiles(issues, read, dir) > 0 {
+		//report what is left after the fixes
+		issues, err = l.Lint(name)
 		if err != nil {
-			panic(err)
+			log.Fatal(context.Background(), "cannot lint file", slog.F("file", cli.Lint.Input), slog.Error(err))
 		}
 	}
+	if cli.Lint.Format == "json" {
+		err = lint.WriteJSON(os.Stdout, issues)
+	} else {
+		lint.Print(os.Stdout, issues)
+	}
+	if err != nil {
+		panic(err)
+	}
+	for _, i := range issues {
+		if i.Severity == diag.Error {
+			os.Exit(1)
+		}
+	}
+}
 
-	return NewFileSplitter(doc, slug, conf, outPath, splitLvl, log)
+// fixFiles applies the autofixes and returns the number of the applied fixes
+func fixFiles(issues []*lint.Issue, read parser.IncludeFunc, dir string) int {
+	byFile := make(map[string][]*lint.Issue)
+	var files []string
+	applied := 0
+	for _, i := range issues {
+		if i.Fix == nil {
+			continue
+		}
+		if byFile[i.Pos.File] == nil {
+			files = append(files, i.Pos.File)
+		}
+		byFile[i.Pos.File] = append(byFile[i.Pos.File], i)
+	}
+	for _, file := range files {
+		src, err := read(file)
+		if err != nil {
+			panic(err)
+		}
+		fixed, n := lint.Apply(src, byFile[file])
+		if err = ioutil.WriteFile(filepath.Join(dir, file), fixed, os.ModePerm); err != nil {
+			panic(err)
+		}
+		log.Info(context.Background(), "fixed file", slog.F("file", file), slog.F("fixes", n))
+		if n < len(byFile[file]) {
+			log.Info(context.Background(), "some fixes overlap, run lint --fix again", slog.F("file", file))
+		}
+		applied += n
+	}
+	return applied
 }
 
 func convert() {
@@ -155,6 +378,17 @@ func convert() {
 		cli.Dump,
 		initConfigCLI(cli.Config, &cli),
 		log)
+	if cli.Convert.Format == "html" {
+		layout, err := html.LoadLayout(cli.Convert.Template)
+		if err != nil {
+			panic(err)
+		}
+		err = splitter.RenderHtml(cli.Convert.ImagePath, layout, cli.Convert.Single)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
 	err := splitter.RenderMarkdown(cli.Convert.ImagePath)
 	if err != nil {
 		panic(err)
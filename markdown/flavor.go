@@ -0,0 +1,239 @@
+package markdown
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/settings"
+	"fmt"
+	"strings"
+)
+
+// Flavor renders the AST constructs which have different syntax in markdown dialects:
+// header ids, admonitions, collapsible blocks, code fences and text escaping.
+type Flavor interface {
+	// Name is the flavor name used in the config and the command line
+	Name() string
+	// HeaderText returns the header text with the custom header id
+	HeaderText(text string, id string) string
+	// Admonition returns the text written before and after the admonition content and the prefix of every content line.
+	// Kind is "NOTE", "TIP", "IMPORTANT", "WARNING", "CAUTION", "EXAMPLE" or "INFO".
+	Admonition(kind string, collapsible bool) (open string, prefix string, close string)
+	// FenceInfo returns the info string of the fenced code block, annotated blocks are followed by the list of callouts
+	FenceInfo(lang string, annotated bool) string
+	// EscapeText escapes the text outside of code spans
+	EscapeText(s string) string
+	// EscapeCode escapes the text of code spans
+	EscapeCode(s string) string
+	// LinkAttrs returns the attributes written after the link
+	LinkAttrs(l *ast.Link) string
+}
+
+var flavors = map[string]Flavor{
+	settings.FlavorMkDocs:     mkDocs{},
+	settings.FlavorGFM:        gfm{},
+	settings.FlavorCommonMark: commonMark{},
+	settings.FlavorHugo:       hugo{},
+	settings.FlavorDocusaurus: docusaurus{},
+}
+
+// FlavorByName returns the markdown flavor, MkDocs Material is the default one
+func FlavorByName(name string) (Flavor, error) {
+	if name == "" {
+		return mkDocs{}, nil
+	}
+	f, ok := flavors[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown markdown flavor: %s", name)
+	}
+	return f, nil
+}
+
+// kindTitle converts "NOTE" to "Note"
+func kindTitle(kind string) string {
+	if kind == "" {
+		return ""
+	}
+	return kind[:1] + strings.ToLower(kind[1:])
+}
+
+// details returns html "<details>" collapsible block which is supported by the most of markdown renderers
+func details(kind string) (string, string, string) {
+	return "<details>\n<summary>" + kindTitle(kind) + "</summary>\n\n", "", "</details>\n"
+}
+
+// mkDocs is MkDocs Material flavor, it requires "attr_list", "admonition", "pymdownx.details"
+// and "pymdownx.superfences" markdown extensions.
+type mkDocs struct{}
+
+func (mkDocs) Name() string {
+	return settings.FlavorMkDocs
+}
+
+func (mkDocs) HeaderText(text string, id string) string {
+	if id == "" {
+		return text
+	}
+	return fmt.Sprintf("%s { #%s }", text, id)
+}
+
+// Admonition returns "!!! note" admonition or "??? note" collapsible block,
+// for details see https://squidfunk.github.io/mkdocs-material/reference/admonitions/.
+func (mkDocs) Admonition(kind string, collapsible bool) (string, string, string) {
+	k := strings.ToLower(kind)
+	if kind == "CAUTION" {
+		k = "danger"
+	}
+	if collapsible {
+		return "??? " + k + "\n", "    ", ""
+	}
+	return "!!! " + k + "\n", "    ", ""
+}
+
+func (mkDocs) FenceInfo(lang string, annotated bool) string {
+	if annotated {
+		// "{ .js .annotate }"
+		return fmt.Sprintf(`{ .%s .annotate }`, lang)
+	}
+	return lang
+}
+
+func (mkDocs) EscapeText(s string) string {
+	return s
+}
+
+func (mkDocs) EscapeCode(s string) string {
+	// insert "word joiner" unicode character (https://www.compart.com/en/unicode/U+2060)
+	// in the middle of "{#" to prevent jinja2 from identifying it as incorrent comment tag
+	return strings.ReplaceAll(s, "{#", "{\u2060#")
+}
+
+func (mkDocs) LinkAttrs(l *ast.Link) string {
+	return linkAttrs(l)
+}
+
+// commonMark is plain CommonMark: admonitions are block quotes, header ids are html anchors.
+type commonMark struct{}
+
+func (commonMark) Name() string {
+	return settings.FlavorCommonMark
+}
+
+func (commonMark) HeaderText(text string, id string) string {
+	if id == "" {
+		return text
+	}
+	return fmt.Sprintf(`<a id="%s"></a>%s`, id, text)
+}
+
+func (commonMark) Admonition(kind string, collapsible bool) (string, string, string) {
+	if collapsible {
+		return details(kind)
+	}
+	return "> **" + kindTitle(kind) + "**\n>\n", "> ", ""
+}
+
+func (commonMark) FenceInfo(lang string, annotated bool) string {
+	return lang
+}
+
+func (commonMark) EscapeText(s string) string {
+	return s
+}
+
+func (commonMark) EscapeCode(s string) string {
+	return s
+}
+
+func (commonMark) LinkAttrs(l *ast.Link) string {
+	return ""
+}
+
+// gfm is GitHub Flavored Markdown, admonitions are written as "> [!NOTE]" alerts.
+type gfm struct {
+	commonMark
+}
+
+func (gfm) Name() string {
+	return settings.FlavorGFM
+}
+
+// GitHub alerts, see https://docs.github.com/en/get-started/writing-on-github/getting-started-with-writing-and-formatting-on-github/basic-writing-and-formatting-syntax#alerts
+var gfmAlerts = map[string]bool{"NOTE": true, "TIP": true, "IMPORTANT": true, "WARNING": true, "CAUTION": true}
+
+func (f gfm) Admonition(kind string, collapsible bool) (string, string, string) {
+	switch {
+	case collapsible:
+		return details(kind)
+	case kind == "INFO":
+		return "> [!NOTE]\n", "> ", ""
+	case gfmAlerts[kind]:
+		return "> [!" + kind + "]\n", "> ", ""
+	}
+	return f.commonMark.Admonition(kind, collapsible)
+}
+
+// hugo writes admonitions as "notice" and "expand" shortcodes of the Relearn theme, header ids are Goldmark attributes.
+type hugo struct {
+	commonMark
+}
+
+func (hugo) Name() string {
+	return settings.FlavorHugo
+}
+
+func (hugo) HeaderText(text string, id string) string {
+	if id == "" {
+		return text
+	}
+	return fmt.Sprintf("%s {#%s}", text, id)
+}
+
+func (hugo) Admonition(kind string, collapsible bool) (string, string, string) {
+	if collapsible {
+		return fmt.Sprintf("{{%% expand \"%s\" %%}}\n", kindTitle(kind)), "", "{{% /expand %}}\n"
+	}
+	if kind == "EXAMPLE" {
+		return "{{% notice info \"Example\" %}}\n", "", "{{% /notice %}}\n"
+	}
+	return "{{% notice " + strings.ToLower(kind) + " %}}\n", "", "{{% /notice %}}\n"
+}
+
+// docusaurus writes ":::note" admonitions, text is escaped to be valid MDX.
+type docusaurus struct {
+	commonMark
+}
+
+func (docusaurus) Name() string {
+	return settings.FlavorDocusaurus
+}
+
+func (docusaurus) HeaderText(text string, id string) string {
+	if id == "" {
+		return text
+	}
+	return fmt.Sprintf("%s {#%s}", text, id)
+}
+
+func (docusaurus) Admonition(kind string, collapsible bool) (string, string, string) {
+	if collapsible {
+		return details(kind)
+	}
+	var k string
+	switch kind {
+	case "NOTE", "TIP", "WARNING", "INFO":
+		k = strings.ToLower(kind)
+	case "CAUTION":
+		k = "danger"
+	case "IMPORTANT":
+		k = "info"
+	default:
+		return ":::note[" + kindTitle(kind) + "]\n", "", ":::\n"
+	}
+	return ":::" + k + "\n", "", ":::\n"
+}
+
+// curly braces start JavaScript expressions in MDX
+var mdxEscaper = strings.NewReplacer("{", `\{`, "}", `\}`)
+
+func (docusaurus) EscapeText(s string) string {
+	return mdxEscaper.Replace(s)
+}
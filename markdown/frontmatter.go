@@ -0,0 +1,92 @@
+package markdown
+
+import (
+	"asciidoc2md/ast"
+	"asciidoc2md/settings"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+)
+
+// NewFrontMatter fills front matter from the split file header, document header and attributes.
+// Non-empty template fields override the generated ones, doc is the name of the document containing the header.
+func NewFrontMatter(root *ast.Document, h *ast.Header, doc string, tmpl ...*settings.FrontMatter) *settings.FrontMatter {
+	var fm settings.FrontMatter
+	if h != nil {
+		fm.Title = h.Text
+		if h.Line > 0 {
+			fm.Source = fmt.Sprintf("%s:%d", doc, h.Line)
+		}
+	}
+	if root.Header != nil {
+		for _, a := range root.Header.Authors {
+			fm.Authors = append(fm.Authors, a.Name)
+		}
+		fm.Date = root.Header.Revision.Date
+		if fm.Title == "" {
+			fm.Title = root.Header.Title
+		}
+	}
+	fm.Description = root.Attributes["description"]
+	for _, k := range strings.Split(root.Attributes["keywords"], ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			fm.Tags = append(fm.Tags, k)
+		}
+	}
+	for _, t := range tmpl {
+		if t != nil {
+			mergeFrontMatter(&fm, t)
+		}
+	}
+	return &fm
+}
+
+func mergeFrontMatter(fm *settings.FrontMatter, t *settings.FrontMatter) {
+	if t.Title != "" {
+		fm.Title = t.Title
+	}
+	if t.Description != "" {
+		fm.Description = t.Description
+	}
+	if len(t.Authors) > 0 {
+		fm.Authors = t.Authors
+	}
+	if t.Date != "" {
+		fm.Date = t.Date
+	}
+	if len(t.Tags) > 0 {
+		fm.Tags = t.Tags
+	}
+	if len(t.Hide) > 0 {
+		fm.Hide = t.Hide
+	}
+	if t.Search != nil {
+		fm.Search = t.Search
+	}
+	if t.Source != "" {
+		fm.Source = t.Source
+	}
+	for k, v := range t.Extra {
+		if fm.Extra == nil {
+			fm.Extra = make(map[string]interface{})
+		}
+		fm.Extra[k] = v
+	}
+}
+
+func WriteFrontMatter(fm *settings.FrontMatter, w io.Writer) error {
+	if _, err := w.Write([]byte("---\n")); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(fm); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte("---\n\n"))
+	return err
+}
@@ -1,12 +1,16 @@
 package markdown
 
 import (
+	"asciidoc2md/asciimath"
 	"asciidoc2md/ast"
+	"asciidoc2md/settings"
+	"asciidoc2md/subs"
 	"asciidoc2md/token"
 	"asciidoc2md/utils"
 	"cdr.dev/slog"
 	"context"
 	"fmt"
+	"html"
 	"io"
 	"regexp"
 	"strings"
@@ -24,6 +28,9 @@ type Converter struct {
 	skipCurChapter bool
 	//writerFile  string
 	idMap	map[string]string//header id to file mapping
+	opts        settings.MarkdownOptions
+	flavor      Flavor
+	inTable     bool //table cells can't contain line breaks
 }
 
 func New(imFolder string, idMap map[string]string, logger slog.Logger, writerFunc GetWriterFunc) *Converter {
@@ -34,6 +41,28 @@ func New(imFolder string, idMap map[string]string, logger slog.Logger, writerFun
 		strictIndent: true}
 }
 
+func (c *Converter) SetOptions(opts settings.MarkdownOptions) {
+	c.opts = opts
+	f, err := FlavorByName(opts.Flavor)
+	if err != nil {
+		c.log.Warn(context.Background(), "mkdocs flavor is used instead", slog.Error(err))
+		f = mkDocs{}
+	}
+	c.flavor = f
+	if c.opts.UIMacros == "" && f.Name() != settings.FlavorMkDocs {
+		//"++ctrl+s++" keys are specific to mkdocs
+		c.opts.UIMacros = settings.UIMacrosHtml
+	}
+}
+
+// getFlavor returns the markdown flavor, MkDocs Material is the default one
+func (c *Converter) getFlavor() Flavor {
+	if c.flavor == nil {
+		return mkDocs{}
+	}
+	return c.flavor
+}
+
 func (c *Converter) RenderMarkdown(doc *ast.Document, w io.Writer) {
 	c.writer = w
 	//c.writerFile = file
@@ -45,20 +74,28 @@ func (c *Converter) WriteDocument(doc *ast.Document) {
 }
 
 func (c *Converter) WriteList(l *ast.List) {
-	//var exp strings.Builder
-	var m = "* "
-	if l.Numbered {
-		m = "1. "
+	if l.Bibliography {
+		c.WriteBibliography(l)
+		return
 	}
+	//var exp strings.Builder
 	indent := c.curIndent
+	if l.Reversed {
+		//markdown numbers items from the first marker up
+		c.log.Warn(context.Background(), "reversed lists are not supported, items are numbered ascending", slog.F("pos", l.Location()))
+	}
+	if l.Start < 0 {
+		c.log.Warn(context.Background(), "list start is below 1, items are numbered from 1", slog.F("pos", l.Location()), slog.F("start", l.Start))
+	}
 
-	for _, i := range l.Items {
+	for n, i := range l.Items {
+		m := listMarker(l, n)
 		if c.strictIndent {
 			c.curIndent = indent + "    " //4 spaces
 		} else {
 			c.curIndent = indent + strings.Repeat(" ", len(m))
 		}
-		c.WriteString("\n" + indent + m)
+		c.WriteString(strings.TrimRight(indent, " ") + "\n" + indent + m)
 		c.WriteContainerBlock(i, false)
 		//c.log.Debug(context.Background(), str)
 		//c.writer.Write([]byte(str))
@@ -66,6 +103,118 @@ func (c *Converter) WriteList(l *ast.List) {
 	c.curIndent = indent
 }
 
+// WriteBibliography writes bibliography entries as definitions: "[label]" is the term, the rest of the entry is its definition.
+// Definitions require "def_list" markdown extension. Entries without "[[[id]]]" anchor are written as list items.
+func (c *Converter) WriteBibliography(l *ast.List) {
+	indent := c.curIndent
+	c.curIndent = indent + "    "
+	for _, item := range l.Items {
+		c.WriteString("\n" + indent)
+		anchor, par := bibAnchor(item)
+		if anchor == nil {
+			c.WriteString("* ")
+			c.WriteContainerBlock(item, false)
+			continue
+		}
+		c.WriteBibAnchor(anchor, c.writer)
+		c.WriteString("\n" + indent + ":   ")
+		blocks := append([]ast.Block{bibEntryText(par)}, item.Blocks[1:]...)
+		c.WriteContainerBlock(&ast.ContainerBlock{Blocks: blocks}, false)
+	}
+	c.curIndent = indent
+}
+
+// bibAnchor returns the anchor the bibliography entry starts with and the paragraph it is in
+func bibAnchor(item *ast.ContainerBlock) (*ast.BibAnchor, *ast.Paragraph) {
+	if len(item.Blocks) == 0 {
+		return nil, nil
+	}
+	par, ok := item.Blocks[0].(*ast.Paragraph)
+	if !ok || len(par.Blocks) == 0 {
+		return nil, nil
+	}
+	anchor, _ := par.Blocks[0].(*ast.BibAnchor)
+	return anchor, par
+}
+
+// bibEntryText returns a copy of the bibliography entry paragraph without the leading anchor
+func bibEntryText(par *ast.Paragraph) *ast.Paragraph {
+	res := *par
+	res.Blocks = nil
+	for i, b := range par.Blocks[1:] {
+		if t, ok := b.(*ast.Text); ok && i == 0 {
+			b = &ast.Text{Text: strings.TrimLeft(t.Text, " \t")}
+		}
+		res.Blocks = append(res.Blocks, b)
+	}
+	return &res
+}
+
+func (c *Converter) WriteBibAnchor(b *ast.BibAnchor, w io.Writer) {
+	w.Write([]byte(fmt.Sprintf(`<a id="%v"></a>**[%s]**`, b.Id, c.fixText(b.Label))))
+}
+
+// listMarker returns a marker of the n-th (zero based) list item: "* ", "1. ", "5. ", "c. ", "IV. ".
+// Alphabetic and roman markers require "pymdownx.fancylists" markdown extension.
+// Markdown counts items up from the first marker, so reversed lists are numbered ascending.
+func listMarker(l *ast.List, n int) string {
+	if !l.Numbered {
+		return "* "
+	}
+	if l.Start == 0 && (l.Style == "" || l.Style == "arabic" || l.Style == "decimal") {
+		//markdown numbers items by itself
+		return "1. "
+	}
+	start := l.Start
+	if start < 1 {
+		//neither markdown nor letters nor roman numbers could go below 1
+		start = 1
+	}
+	num := start + n
+	switch l.Style {
+	case "loweralpha":
+		return alphaNumber(num) + ". "
+	case "upperalpha":
+		return strings.ToUpper(alphaNumber(num)) + ". "
+	case "lowerroman":
+		return strings.ToLower(romanNumber(num)) + ". "
+	case "upperroman":
+		return romanNumber(num) + ". "
+	}
+	return fmt.Sprintf("%v. ", num)
+}
+
+// alphaNumber converts 1 -> "a", 26 -> "z", 27 -> "aa".
+func alphaNumber(n int) string {
+	var res []byte
+	for n > 0 {
+		n--
+		res = append([]byte{byte('a' + n%26)}, res...)
+		n /= 26
+	}
+	return string(res)
+}
+
+var romanNumerals = []struct {
+	value int
+	digits string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+	{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+// romanNumber converts 4 -> "IV", 1994 -> "MCMXCIV".
+func romanNumber(n int) string {
+	var res strings.Builder
+	for _, r := range romanNumerals {
+		for n >= r.value {
+			res.WriteString(r.digits)
+			n -= r.value
+		}
+	}
+	return res.String()
+}
+
 // ConvertComplexTable converts complex table into a list.
 // For example, if input table has 3 columns, then exp list would be:
 //  * _col1 header:_ (like italic)
@@ -142,6 +291,10 @@ func (c *Converter) WriteTable(t *ast.Table) {
 	//var exp strings.Builder
 	//indent := c.curIndent
 
+	if len(t.Spans) > 0 {
+		//markdown tables have no spans
+		t = t.Expanded()
+	}
 	if !t.IsSimple() {
 		c.WriteList(c.ConvertComplexTable(t))
 		return
@@ -161,6 +314,8 @@ func (c *Converter) WriteTable(t *ast.Table) {
 	t.Header = true
 	row := 0
 	col := 1
+	c.inTable = true
+	defer func() { c.inTable = false }()
 	for i, cell := range t.Cells {
 		 if i % t.Columns == 0 {
 		 	//new row
@@ -195,7 +350,7 @@ func (c *Converter) WriteTable(t *ast.Table) {
 }
 
 func (c *Converter) WriteBlockTitle(h *ast.BlockTitle, w io.Writer) {
-	w.Write([]byte(fmt.Sprintf("_%v_\n", strings.TrimSpace(fixText(h.Title)))))
+	w.Write([]byte(fmt.Sprintf("_%v_\n", strings.TrimSpace(c.fixText(h.Title)))))
 }
 
 func (c *Converter) WriteHeader(h *ast.Header, w io.Writer) {
@@ -206,34 +361,56 @@ func (c *Converter) WriteHeader(h *ast.Header, w io.Writer) {
 	 */
 	if h.Float {
 		//render float headers as italic text
-		w.Write([]byte("_" + fixText(h.Text) + "_\n"))
+		w.Write([]byte("_" + c.fixText(h.Text) + "_\n"))
 		return
 	}
-	anchor := "\n"
-	if h.Id != "" {
-		anchor = fmt.Sprintf(" { #%s }\n", h.Id)
-	}
-	w.Write([]byte(strings.Repeat("#", h.Level) + " " + fixText(h.Text) + anchor))
+	w.Write([]byte(strings.Repeat("#", h.Level) + " " + c.getFlavor().HeaderText(c.fixText(h.Title()), h.Id) + "\n"))
 
 
 }
 
-//WriteAdmonition will work only if "Admonition" markdown extension is enabled.
-//For details see https://squidfunk.github.io/mkdocs-material/reference/admonitions/.
 func (c *Converter) WriteAdmonition(a *ast.Admonition) {
 	//writer == "NOTE:" || writer == "TIP:" || writer == "IMPORTANT:" || writer == "WARNING:" || writer == "CAUTION:":
-	var kind string
-	if a.Kind == "CAUTION" {
-		kind = "danger"
-	} else {
-		kind = strings.ToLower(a.Kind)
-	}
-	c.WriteString(fmt.Sprintf("!!! %s\n%v    ", kind, c.curIndent))
-	c.WriteContainerBlock(a.Content, false)
+	c.writeAdmonition(strings.ToUpper(a.Kind), false, a.Content)
 	//c.WriteParagraph(a.Content, false, w)
 	c.WriteString("\n")
 }
 
+// writeAdmonition writes admonitions and collapsible blocks in the syntax of the markdown flavor
+func (c *Converter) writeAdmonition(kind string, collapsible bool, content *ast.ContainerBlock) {
+	open, prefix, close := c.getFlavor().Admonition(kind, collapsible)
+	ind := c.curIndent
+	c.WriteString(indentLines(open, ind))
+	c.curIndent = ind + prefix
+	c.WriteContainerBlock(content, true)
+	c.curIndent = ind
+	if close != "" {
+		c.WriteString("\n" + ind + indentLines(close, ind))
+	}
+}
+
+// indentLines indents every line of s except the first one
+func indentLines(s string, indent string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.ReplaceAll(s, "\n", "\n"+indent) + "\n"
+}
+
+// blankLine returns the empty line of the current indentation level, block quote markers are kept
+func (c *Converter) blankLine() string {
+	return strings.TrimRight(c.curIndent, " ") + "\n"
+}
+
+func (c *Converter) WriteLineBreak(w io.Writer) {
+	switch {
+	case c.inTable:
+		w.Write([]byte("<br>"))
+	case c.opts.HardBreak == settings.HardBreakBackslash:
+		w.Write([]byte("\\\n" + c.curIndent))
+	default:
+		w.Write([]byte("  \n" + c.curIndent))
+	}
+}
+
 func (c *Converter) ConvertParagraph(p *ast.Paragraph, noFormatFix bool) string {
 	var res strings.Builder
 	c.WriteParagraph(p, noFormatFix, &res)
@@ -248,12 +425,10 @@ var boldWrappedRE = regexp.MustCompile(`^\*(.*)\*$`)
 // "`+++strange formatting+++`"
 var passThruMarkRE = regexp.MustCompile(`\+{3}`)
 var passThruRE = regexp.MustCompile(`\+{3}.+?\+{3}`)
-var checkedRE = regexp.MustCompile(`^\[\*\]`)
 // match only single stars "*" pairs at word boundary and ignore single "*" without pair and double stars "**"
 //var boldRE = regexp.MustCompile(`([^\*]|^)\B\*\b|\b\*\B([^\*]|$)`)
 var boldRE = regexp.MustCompile(`(\s|[[:punct:]]|^)\*([^\s\*])(.+?)([^\s\*])\*(\s|[[:punct:]]|$)`)
 var sharpSpaceRE = regexp.MustCompile(`#(\s)`) // "# text" patterns. Need to escape sharp symbol.
-var hardBreakRE = regexp.MustCompile(`\s\+\s*$`)
 var smallTextRE = regexp.MustCompile(`\[small]#(.*?)#`)
 var sharpTextRE = regexp.MustCompile(`(#(?:[^\s[:punct:]]|_)+)`) // "#name_id some text"-like patterns outside of backticked spans.
 var mdEscAllRE = regexp.MustCompile(`([\\\x60*_{}[\]\(\)#\+-\.!\|])`) // `<>` signs are excluded since there is specific rule for them
@@ -269,9 +444,9 @@ func fixString(s string, backticked bool) string {
 		s = boldWrappedRE.ReplaceAllString(s, "$1")
 		// remove "+++text+++" wrappers
 		s = passThruMarkRE.ReplaceAllLiteralString(s, "")
-		// insert "word joiner" unicode character (https://www.compart.com/en/unicode/U+2060)
-		// in the middle of "{#" to prevent jinja2 from identifying it as incorrent comment tag
-		s = strings.ReplaceAll(s, "{#", "{\u2060#")
+		// asciidoc magic "`Section1.Field1\=>Section2.Field2`", replacements aren't applied to monospace text
+		s = strings.ReplaceAll(s, `\->`, `->`)
+		s = strings.ReplaceAll(s, `\=>`, `=>`)
 	}
 	if !backticked {
 		// fix html passthru syntax "+++some * text # here+++"
@@ -284,13 +459,10 @@ func fixString(s string, backticked bool) string {
 		s = sharpTextRE.ReplaceAllString(s, "`$1`")
 		// escaping all markdown special symbols
 		s = mdEscTextRE.ReplaceAllString(s, `$1\$2`)
-		// fix checked lists "[*]" -> "[x]"
-		s = checkedRE.ReplaceAllLiteralString(s, "[x]")
 		// converting "*" (asciidoc bold) to "**" (markdown bold)
 		// no need to convert asciidoc italic "_" since it's still an italic in markdown
 		s = boldRE.ReplaceAllString(s, "$1**$2$3$4**$5")
 		//s = sharpSpaceRE.ReplaceAllString(s, `\#$1`)
-		s = strings.ReplaceAll(s, "->", "→")
 		s = strings.ReplaceAll(s, "<", "&lt;")
 		s = strings.ReplaceAll(s, ">", "&gt;")
 	}
@@ -298,13 +470,25 @@ func fixString(s string, backticked bool) string {
 }
 
 func fixText(s string) string {
-	// asciidoc magic "Section1.Field1\=>Section2.Field2"
-	s = strings.ReplaceAll(s, `\->`, `->`)
-	s = strings.ReplaceAll(s, `\=>`, `=>`)
+	return fixTextWith(s, true, mkDocs{})
+}
+
+// fixText converts the text using the converter markdown flavor
+func (c *Converter) fixText(s string) string {
+	return fixTextWith(s, true, c.getFlavor())
+}
+
+var escapedURLRE = regexp.MustCompile(`\+\+((?:https?|ftp|irc)://[^\s+]+)\+\+`)
+
+// fixTextWith converts asciidoc inline formatting to markdown, replacements ("(C)", "--", "=>", ...)
+// are applied to the text outside of monospace spans if enabled.
+func fixTextWith(s string, replacements bool, f Flavor) string {
 	// replace NBSP with ordinary space
 	s = strings.ReplaceAll(s, "\u00a0", " ")
 	// removing "[small]#small text# magic"
 	s = smallTextRE.ReplaceAllString(s, "$1")
+	// "++https://example.org++" is the URL which isn't a link
+	s = escapedURLRE.ReplaceAllString(s, "$1")
 	var fixed = strings.Builder{}
 	indices := append(append([][]int{}, backticksRE.FindAllStringIndex(s, -1)...), []int{len(s),-1})
 	beg := 0
@@ -313,18 +497,21 @@ func fixText(s string) string {
 		s1 = s[beg:ind[0]]
 		if len(s1) > 0 {
 			//fmt.Printf("'%s'\n", s1)
-			fixed.WriteString(fixString(s1, false))
+			if replacements {
+				s1 = subs.Replacements(s1)
+			}
+			fixed.WriteString(f.EscapeText(fixString(s1, false)))
 		}
 		if ind[1] != -1 {
 			s2 = s[ind[0]+1:ind[1]-1] //exclude backticks
 			fixed.WriteRune('`')
-			fixed.WriteString(fixString(s2, true))
+			fixed.WriteString(f.EscapeCode(fixString(s2, true)))
 			fixed.WriteRune('`')
 			//fmt.Printf("'%s'\n", s2)
 		}
 		beg = ind[1]
 	}
-	return hardBreakRE.ReplaceAllString(fixed.String(), `<br>`)
+	return fixed.String()
 }
 
 func (c *Converter) WriteParagraph(p *ast.Paragraph, noFormatFix bool, w io.Writer) {
@@ -339,9 +526,35 @@ func (c *Converter) WriteParagraph(p *ast.Paragraph, noFormatFix bool, w io.Writ
 				continue
 			}
 			if !noFormatFix {
-				str = fixText(str)
+				str = fixTextWith(str, !p.NoReplacements, c.getFlavor())
 			}
 			w.Write([]byte(str))
+		case *ast.CheckBox:
+			//"pymdownx.tasklist" markdown extension syntax
+			if b.(*ast.CheckBox).Checked {
+				w.Write([]byte("[x] "))
+			} else {
+				w.Write([]byte("[ ] "))
+			}
+		case *ast.LineBreak:
+			c.WriteLineBreak(w)
+		case *ast.IndexTerm:
+			//concealed terms are only listed in the index
+			if t := b.(*ast.IndexTerm); t.Visible {
+				w.Write([]byte(c.fixText(t.Terms[0])))
+			}
+		case *ast.Bookmark:
+			w.Write([]byte(fmt.Sprintf(`<a id="%v"></a>`, b.(*ast.Bookmark).Literal)))
+		case *ast.BibAnchor:
+			c.WriteBibAnchor(b.(*ast.BibAnchor), w)
+		case *ast.InlineStem:
+			c.WriteInlineStem(b.(*ast.InlineStem), w)
+		case *ast.Kbd:
+			c.WriteKbd(b.(*ast.Kbd), w)
+		case *ast.Button:
+			c.WriteButton(b.(*ast.Button), w)
+		case *ast.Menu:
+			c.WriteMenu(b.(*ast.Menu), w)
 		case *ast.InlineImage:
 			c.WriteInlineImage(b.(*ast.InlineImage), w)
 		case *ast.Link:
@@ -351,30 +564,17 @@ func (c *Converter) WriteParagraph(p *ast.Paragraph, noFormatFix bool, w io.Writ
 }
 
 func (c *Converter) WriteExampleBlock(ex *ast.ExampleBlock) {
-	ind := c.curIndent
-	c.curIndent += "    "
-	if ex.Collapsible {
-		c.writer.Write([]byte("???"))
-	} else {
-		c.writer.Write([]byte("!!!"))
-	}
 	var k string
 	switch {
-	case ex.Kind == "CAUTION":
-		//convert to "danger"
-		k = "danger"
 	case ex.Kind != "":
-		//all others
-		k = strings.ToLower(ex.Kind)
+		k = strings.ToUpper(ex.Kind)
 	case ex.Delim.Type == token.EX_BLOCK:
 		//just an example block
-		k = "example"
+		k = "EXAMPLE"
 	default:
-		k = "info"
+		k = "INFO"
 	}
-	c.writer.Write([]byte(" " + k + "\n"))
-	c.WriteContainerBlock(&ex.ContainerBlock, true)
-	c.curIndent = ind
+	c.writeAdmonition(k, ex.Collapsible, &ex.ContainerBlock)
 }
 
 
@@ -386,17 +586,22 @@ func (c *Converter) WriteString(s string) error {
 func (c *Converter) WriteContainerBlock(p *ast.ContainerBlock, firstLineIndent bool)  {
 	//var exp strings.Builder
 
+	written := 0 //count of written blocks, skipped comments aren't counted
 	for i, b := range p.Blocks {
 
+		if _, isComment := b.(*ast.Comment); isComment && !c.opts.KeepComments {
+			continue
+		}
 		_, isList := b.(*ast.List)
 		_, isTable := b.(*ast.Table)
-		if i > 0 {
+		if written > 0 {
 			//write extra newline before every paragraph, except the first one
-			c.WriteString("\n")
+			c.WriteString(c.blankLine())
 		}
-		if !isList && !isTable && ((i == 0 && firstLineIndent) || i > 0) {
+		if !isList && !isTable && ((written == 0 && firstLineIndent) || written > 0) {
 			c.WriteString(c.curIndent)
 		}
+		written++
 
 		switch b.(type) {
 		case *ast.Header:
@@ -425,6 +630,10 @@ func (c *Converter) WriteContainerBlock(p *ast.ContainerBlock, firstLineIndent b
 			c.WriteTable(b.(*ast.Table))
 		case *ast.Image:
 			c.WriteImage(b.(*ast.Image), c.writer)
+		case *ast.Video:
+			c.WriteVideo(b.(*ast.Video))
+		case *ast.Audio:
+			c.WriteAudio(b.(*ast.Audio))
 		case *ast.Paragraph:
 			c.WriteParagraph(b.(*ast.Paragraph), false, c.writer)
 			c.WriteString("\n")
@@ -461,11 +670,23 @@ func (c *Converter) WriteContainerBlock(p *ast.ContainerBlock, firstLineIndent b
 						//c.WriteParagraph(ast.NewParagraphFromStr("_Выноски:_"), true, c.writer)
 					}
 				} else {
-					c.log.Error(context.Background(), "cannot find connected list of annotations", slog.F("syntax block", sb.StringWithIndent("")))
+					c.log.Error(context.Background(), "cannot find connected list of annotations", slog.F("pos", sb.Location()), slog.F("syntax block", sb.StringWithIndent("")))
 				}
 			}
 		case *ast.Bookmark:
 			c.WriteString(fmt.Sprintf(`<a id="%v"></a>`, b.(*ast.Bookmark).Literal))
+		case *ast.Comment:
+			c.WriteComment(b.(*ast.Comment))
+		case *ast.Toc:
+			c.WriteToc(b.(*ast.Toc))
+		case *ast.Stem:
+			c.WriteStem(b.(*ast.Stem))
+		case *ast.PassBlock:
+			c.WritePassBlock(b.(*ast.PassBlock))
+		case *ast.Unknown:
+			c.WriteUnknown(b.(*ast.Unknown))
+		case *ast.DiagramBlock:
+			c.WriteDiagram(b.(*ast.DiagramBlock))
 
 		default:
 			panic("invalid ast block\n" + b.StringWithIndent(""))
@@ -473,12 +694,226 @@ func (c *Converter) WriteContainerBlock(p *ast.ContainerBlock, firstLineIndent b
 	}
 }
 
+func (c *Converter) WriteToc(t *ast.Toc) {
+	if t.Title != "" {
+		c.WriteString("_" + c.fixText(t.Title) + "_\n\n" + c.curIndent)
+	}
+	c.writeTocEntries(t.Entries, c.curIndent)
+}
+
+func (c *Converter) writeTocEntries(entries []*ast.TocEntry, indent string) {
+	for i, e := range entries {
+		if i > 0 {
+			c.WriteString(indent)
+		}
+		c.WriteString(fmt.Sprintf("* [%s](%s)\n", c.fixText(e.Text), e.Url))
+		if len(e.Entries) > 0 {
+			c.WriteString(indent + "    ")
+			c.writeTocEntries(e.Entries, indent + "    ")
+		}
+	}
+}
+
+// latex returns the formula in LaTeX notation, false is returned if AsciiMath formula is left as is
+func (c *Converter) latex(pos ast.Pos, notation string, text string) (string, bool) {
+	if notation != ast.AsciiMath {
+		return text, true
+	}
+	if c.opts.KeepAsciiMath {
+		return text, false
+	}
+	tex, err := asciimath.ToLatex(text)
+	if err != nil {
+		c.log.Warn(context.Background(), "cannot convert AsciiMath to LaTeX", slog.F("pos", pos.Location()), slog.F("formula", text), slog.Error(err))
+		return text, false
+	}
+	return tex, true
+}
+
+// AsciiMath delimiters are backticks, they are written as html entities to not become code spans
+var asciiMathEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "&#96;", "<", "&lt;")
+
+//WriteStem writes the formula in "pymdownx.arithmatex" generic format: "\[...\]".
+func (c *Converter) WriteStem(s *ast.Stem) {
+	tex, ok := c.latex(s.Pos, s.Notation, s.Text)
+	if !ok {
+		c.WriteString("&#96;" + asciiMathEscaper.Replace(tex) + "&#96;\n")
+		return
+	}
+	lines := strings.Split(tex, "\n")
+	c.WriteString("\\[\n")
+	for _, line := range lines {
+		c.WriteString(c.curIndent + line + "\n")
+	}
+	c.WriteString(c.curIndent + "\\]\n")
+}
+
+//WriteInlineStem writes the formula in "pymdownx.arithmatex" generic format: "\(...\)".
+func (c *Converter) WriteInlineStem(s *ast.InlineStem, w io.Writer) {
+	tex, ok := c.latex(s.Pos, s.Notation, s.Text)
+	if !ok {
+		w.Write([]byte("&#96;" + asciiMathEscaper.Replace(tex) + "&#96;"))
+		return
+	}
+	w.Write([]byte(`\(` + tex + `\)`))
+}
+
+// mediaFragment returns "#t=start,end" media fragment of the local audio or video file
+func mediaFragment(m *ast.MediaOptions) string {
+	if m.Start == "" && m.End == "" {
+		return ""
+	}
+	start := m.Start
+	if start == "" {
+		start = "0"
+	}
+	if m.End == "" {
+		return "#t=" + start
+	}
+	return "#t=" + start + "," + m.End
+}
+
+// mediaAttrs returns boolean attributes of the html media element
+func mediaAttrs(m *ast.MediaOptions) string {
+	var res string
+	if m.Autoplay {
+		res += " autoplay"
+	}
+	if m.Loop {
+		res += " loop"
+	}
+	if !m.NoControls {
+		res += " controls"
+	}
+	return res
+}
+
+//WriteVideo writes youtube and vimeo videos as "<iframe>", video files as "<video>" html elements.
+func (c *Converter) WriteVideo(v *ast.Video) {
+	var size string
+	if v.Width != "" {
+		size += fmt.Sprintf(` width="%s"`, html.EscapeString(v.Width))
+	}
+	if v.Height != "" {
+		size += fmt.Sprintf(` height="%s"`, html.EscapeString(v.Height))
+	}
+	var params []string
+	switch v.Provider {
+	case "youtube":
+		if v.Start != "" {
+			params = append(params, "start="+v.Start)
+		}
+		if v.End != "" {
+			params = append(params, "end="+v.End)
+		}
+		if v.Autoplay {
+			params = append(params, "autoplay=1")
+		}
+		if v.Loop {
+			//youtube loops a playlist only
+			params = append(params, "loop=1", "playlist="+v.Target)
+		}
+		if v.NoControls {
+			params = append(params, "controls=0")
+		}
+		src := "https://www.youtube.com/embed/" + v.Target
+		if len(params) > 0 {
+			src += "?" + strings.Join(params, "&amp;")
+		}
+		c.WriteString(fmt.Sprintf(`<iframe%s src="%s" frameborder="0" allowfullscreen></iframe>`+"\n", size, src))
+	case "vimeo":
+		if v.Autoplay {
+			params = append(params, "autoplay=1")
+		}
+		if v.Loop {
+			params = append(params, "loop=1")
+		}
+		src := "https://player.vimeo.com/video/" + v.Target
+		if len(params) > 0 {
+			src += "?" + strings.Join(params, "&amp;")
+		}
+		if v.Start != "" {
+			src += "#at=" + v.Start
+		}
+		c.WriteString(fmt.Sprintf(`<iframe%s src="%s" frameborder="0" allowfullscreen></iframe>`+"\n", size, src))
+	default:
+		attrs := mediaAttrs(&v.MediaOptions)
+		if v.Muted {
+			attrs += " muted"
+		}
+		if v.Poster != "" {
+			attrs += fmt.Sprintf(` poster="%s"`, c.imagePath(v.Poster))
+		}
+		c.WriteString(fmt.Sprintf(`<video src="%s%s"%s%s>Your browser does not support the video tag.</video>`+"\n",
+			c.imagePath(v.Target), mediaFragment(&v.MediaOptions), size, attrs))
+	}
+}
+
+func (c *Converter) WriteAudio(a *ast.Audio) {
+	c.WriteString(fmt.Sprintf(`<audio src="%s%s"%s>Your browser does not support the audio tag.</audio>`+"\n",
+		c.imagePath(a.Target), mediaFragment(&a.MediaOptions), mediaAttrs(&a.MediaOptions)))
+}
+
+// default format of the images generated by Asciidoctor Diagram
+const defaultDiagramFormat = "png"
+
+//WriteDiagram writes the diagram source as a fenced block: mkdocs-material renders "mermaid" fences,
+//"plantuml" fences require mkdocs-build-plantuml plugin. Pre-rendered image is referenced if it's enabled by the options.
+func (c *Converter) WriteDiagram(d *ast.DiagramBlock) {
+	if c.opts.DiagramImages && d.Target != "" {
+		format := d.Format
+		if format == "" {
+			format = defaultDiagramFormat
+		}
+		c.WriteImage(&ast.Image{Path: d.Target + "." + format}, c.writer)
+		return
+	}
+	str := strings.Trim(d.Literal, "\n")
+	str = strings.ReplaceAll(str, "\n", "\n"+c.curIndent)
+	c.WriteString(fmt.Sprintf("``` %s\n%s%s\n%s```\n", d.Type, c.curIndent, str, c.curIndent))
+}
+
+func (c *Converter) WritePassBlock(pb *ast.PassBlock) {
+	c.WriteString(strings.TrimRight(pb.Literal, "\n") + "\n")
+}
+
+// WriteUnknown writes the source text the parser failed to parse as is
+func (c *Converter) WriteUnknown(u *ast.Unknown) {
+	c.WriteString(strings.ReplaceAll(strings.TrimRight(u.Text, "\n"), "\n", "\n"+c.curIndent) + "\n")
+}
+
+func (c *Converter) WriteComment(cm *ast.Comment) {
+	// "--" isn't allowed inside html comments, "---" gives "- --" after the single replacement
+	text := cm.Text
+	for strings.Contains(text, "--") {
+		text = strings.ReplaceAll(text, "--", "- -")
+	}
+	if !strings.Contains(text, "\n") {
+		c.WriteString("<!-- " + text + " -->\n")
+		return
+	}
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(c.curIndent + lines[i], " \t\r")
+	}
+	c.WriteString("<!--\n" + strings.Join(lines, "\n") + "\n" + c.curIndent + "-->\n")
+}
+
+// imagePath returns the path of the image or the media file in the images folder
+func (c *Converter) imagePath(path string) string {
+	if strings.Contains(path, "://") {
+		//remote file
+		return path
+	}
+	return c.imageFolder + strings.ReplaceAll(path, `\`, `/`)
+}
+
 func (c *Converter) WriteImage(p *ast.Image, w io.Writer) {
-	w.Write([]byte(fmt.Sprintf("![](%v)\n", c.imageFolder+ strings.ReplaceAll(p.Path, `\`, `/`))))
+	w.Write([]byte(fmt.Sprintf("![](%v)\n", c.imagePath(p.Path))))
 }
 
 func (c *Converter) WriteInlineImage(p *ast.InlineImage, w io.Writer) {
-	w.Write([]byte("![](" + c.imageFolder+ strings.ReplaceAll(p.Path, `\`, `/`) + ")"))
+	w.Write([]byte("![](" + c.imagePath(p.Path) + ")"))
 }
 
 func (c *Converter) WriteHorLine(p *ast.HorLine, w io.Writer) {
@@ -492,7 +927,109 @@ func (c *Converter) WriteLink(l *ast.Link, w io.Writer)  {
 		c.log.Debug(context.Background(), "empty link caption", slog.F("link", l))
 		caption = l.Url
 	}
-	w.Write([]byte(fmt.Sprintf("[%s](%s)", fixText(caption), l.Url)))
+	w.Write([]byte(fmt.Sprintf("[%s](%s)%s", c.fixText(caption), l.Url, c.getFlavor().LinkAttrs(l))))
+}
+
+// linkAttrs returns "{ .role target=_blank }" link attributes, "attr_list" markdown extension is required
+func linkAttrs(l *ast.Link) string {
+	var attrs []string
+	for _, r := range l.Roles {
+		attrs = append(attrs, "."+r)
+	}
+	if l.Window != "" {
+		attrs = append(attrs, "target="+l.Window)
+	}
+	if len(attrs) == 0 {
+		return ""
+	}
+	return "{ " + strings.Join(attrs, " ") + " }"
+}
+
+// pymdownx.keys key names, see https://facelessuser.github.io/pymdown-extensions/extensions/keys/#key-map-index
+var keyNames = map[string]string{
+	"+":         "plus",
+	"esc":       "escape",
+	"del":       "delete",
+	"ins":       "insert",
+	"pgup":      "page-up",
+	"pgdn":      "page-down",
+	"pageup":    "page-up",
+	"pagedown":  "page-down",
+	"up":        "arrow-up",
+	"down":      "arrow-down",
+	"left":      "arrow-left",
+	"right":     "arrow-right",
+	"↑":         "arrow-up",
+	"↓":         "arrow-down",
+	"←":         "arrow-left",
+	"→":         "arrow-right",
+	"⌘":         "cmd",
+	"command":   "cmd",
+	"⇧":         "shift",
+	"⌥":         "option",
+	"win":       "windows",
+	"backspace": "backspace",
+	"enter":     "enter",
+	"return":    "enter",
+	"space":     "space",
+	"tab":       "tab",
+}
+
+func keyName(k string) string {
+	name := strings.ToLower(k)
+	if n, ok := keyNames[name]; ok {
+		return n
+	}
+	if strings.ContainsAny(name, " +") {
+		//custom key
+		return `"` + k + `"`
+	}
+	return name
+}
+
+func (c *Converter) WriteKbd(k *ast.Kbd, w io.Writer) {
+	keys := make([]string, 0, len(k.Keys))
+	if c.opts.UIMacros == settings.UIMacrosHtml {
+		for _, key := range k.Keys {
+			keys = append(keys, "<kbd>"+html.EscapeString(key)+"</kbd>")
+		}
+		w.Write([]byte(strings.Join(keys, "+")))
+		return
+	}
+	for _, key := range k.Keys {
+		keys = append(keys, keyName(key))
+	}
+	w.Write([]byte("++" + strings.Join(keys, "+") + "++"))
+}
+
+func (c *Converter) WriteButton(b *ast.Button, w io.Writer) {
+	if c.opts.UIMacros == settings.UIMacrosHtml {
+		w.Write([]byte(`<span class="btn">` + html.EscapeString(b.Text) + `</span>`))
+		return
+	}
+	w.Write([]byte("**" + c.fixText(b.Text) + "**"))
+}
+
+func (c *Converter) WriteMenu(m *ast.Menu, w io.Writer) {
+	items := make([]string, 0, len(m.Items))
+	if c.opts.UIMacros == settings.UIMacrosHtml {
+		for i, item := range m.Items {
+			class := "submenu"
+			switch {
+			case i == 0:
+				class = "menu"
+			case i == len(m.Items) - 1:
+				class = "menuitem"
+			}
+			items = append(items, fmt.Sprintf(`<b class="%s">%s</b>`, class, html.EscapeString(item)))
+		}
+		w.Write([]byte(`<span class="menuseq">` + strings.Join(items, `&#160;<b class="caret">&#8250;</b> `) + `</span>`))
+		return
+	}
+	for _, item := range m.Items {
+		items = append(items, "**"+c.fixText(item)+"**")
+	}
+	w.Write([]byte(strings.Join(items, " → ")))
 }
 
 var calloutRE = regexp.MustCompile(`<(\.|\d+)>`)
@@ -537,10 +1074,6 @@ func (c *Converter) WriteSyntaxBlock(sb *ast.SyntaxBlock) {
 		return
 	}
 	str = strings.ReplaceAll(str,"\n", "\n" + c.curIndent)
-	lang := sb.Lang
-	if hasAnn {
-		// "{ .js .annotate }"
-		lang = fmt.Sprintf(`{ .%s .annotate }`, sb.Lang)
-	}
+	lang := c.getFlavor().FenceInfo(sb.Lang, hasAnn)
 	c.WriteString(fmt.Sprintf("``` %s\n%s%s\n%s```\n", lang, c.curIndent, str, c.curIndent))
 }
\ No newline at end of file
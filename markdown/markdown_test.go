@@ -1,7 +1,9 @@
 package markdown
 
 import (
+	"asciidoc2md/ast"
 	"asciidoc2md/parser"
+	"asciidoc2md/settings"
 	"asciidoc2md/utils"
 	"bufio"
 	"cdr.dev/slog"
@@ -51,6 +53,195 @@ example text
 `,
 		exp:
 `## Версия 3.6 { #v3.6 }
+`,
+	},
+	{
+		name: "table spans",
+		input: "|===\n|A |B\n2+|wide\n|===",
+		//spanned cells are padded with empty ones
+		exp: "| <div style=\"width:13em\">A </div> |B |\n|  --- | --- |\n| wide | |\n",
+	},
+	{
+		name: "checklist",
+		input: `* [*] done
+* [ ] todo`,
+		exp: `
+* [x] done
+
+* [ ] todo
+`,
+	},
+	{
+		name: "ordered list start",
+		input: `[start=5]
+. five
+. six`,
+		exp: `
+5. five
+
+6. six
+`,
+	},
+	{
+		name: "ordered list style",
+		input: `[loweralpha%reversed]
+. b
+. a`,
+		exp: `
+a. b
+
+b. a
+`,
+	},
+	{
+		name: "index terms",
+		input: `A ((visible)) term (((concealed))) and anchor:id1[] here`,
+		exp: `A visible term  and <a id="id1"></a> here
+`,
+	},
+	{
+		name: "ui macros",
+		input: `:experimental:
+
+Press kbd:[Ctrl+Shift+Esc], btn:[Save] or menu:File[Save As].`,
+		exp: `Press ++ctrl+shift+escape++, **Save** or **File** → **Save As**.
+`,
+	},
+	{
+		name: "replacements",
+		input: `(C) 2021 -- a -> b, ` + "`a -> b`" + ` and +++(C)+++
+
+[subs=-replacements]
+(C) a -> b`,
+		exp: `© 2021 — a → b, ` + "`a -> b`" + ` and \(C\)
+
+(C) a -&gt; b
+`,
+	},
+	{
+		name: "comments",
+		input: `// comment
+* item
++
+////
+block -- comment
+////
+* item2`,
+		exp: `
+* item
+
+* item2
+`,
+	},
+	{
+		name: "line breaks",
+		input: `* first +
+second`,
+		exp: `
+* first  
+  second
+`,
+	},
+	{
+		name: "bibliography",
+		input: `[bibliography]
+* [[[pp]]] Andy Hunt & Dave Thomas. The Pragmatic Programmer.
+* [[[gof,GoF]]] Erich Gamma et al. Design Patterns.`,
+		exp: `
+<a id="pp"></a>**[pp]**
+:   Andy Hunt & Dave Thomas. The Pragmatic Programmer.
+
+<a id="gof"></a>**[GoF]**
+:   Erich Gamma et al. Design Patterns.
+`,
+	},
+	{
+		name: "bibliography entry without anchor",
+		input: `[bibliography]
+* [[[pp]]] Andy Hunt & Dave Thomas. The Pragmatic Programmer.
+* Erich Gamma et al. Design Patterns.`,
+		exp: `
+<a id="pp"></a>**[pp]**
+:   Andy Hunt & Dave Thomas. The Pragmatic Programmer.
+
+* Erich Gamma et al. Design Patterns.
+`,
+	},
+	{
+		name: "stem",
+		input: `[stem]
+++++
+sqrt(x^2+1)
+++++
+
+Inline stem:[a/b] and latexmath:[\alpha].`,
+		exp: `\[
+\sqrt{x^{2}+1}
+\]
+
+Inline \(\frac{a}{b}\) and \(\alpha\).
+`,
+	},
+	{
+		name: "diagrams",
+		input: `* item
++
+[mermaid]
+----
+graph TD
+  A --> B
+----`,
+		exp: `
+* item
+
+  ` + "``` mermaid" + `
+  graph TD
+    A --> B
+  ` + "```" + `
+`,
+	},
+	{
+		name: "media",
+		input: `video::rPQoq7ThGAU[youtube, 640, 360, start=60, options=autoplay]
+
+video::intro.mp4[width=640, poster=intro.png, start=10, end=20, options="loop,muted"]
+
+audio::sound.mp3[options=nocontrols]`,
+		exp: `<iframe width="640" height="360" src="https://www.youtube.com/embed/rPQoq7ThGAU?start=60&amp;autoplay=1" frameborder="0" allowfullscreen></iframe>
+
+<video src="data/images/intro.mp4#t=10,20" width="640" loop controls muted poster="data/images/intro.png">Your browser does not support the video tag.</video>
+
+<audio src="data/images/sound.mp3">Your browser does not support the audio tag.</audio>
+`,
+	},
+	{
+		name: "link attributes",
+		input: `Open https://a.org[Docs, window=_blank, role=ext] or write to e@f.org, not ++https://a.org++.`,
+		exp: `Open [Docs](https://a.org){ .ext target=_blank } or write to [e@f.org](mailto:e@f.org), not https://a.org.
+`,
+	},
+	{
+		name: "section numbers",
+		input: `= Doc
+:sectnums:
+
+== One
+
+=== One.One
+
+[appendix]
+== Extra
+
+=== Details`,
+		exp: `# Doc
+
+## 1. One
+
+### 1.1. One.One
+
+## Appendix A. Extra
+
+### A.1. Details
 `,
 	},
 }
@@ -109,6 +300,210 @@ func TestAll(t *testing.T) {
 	}
 }
 
+func TestLineBreakStyle(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	doc, err := parser.New("a +\nb", nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	w := strings.Builder{}
+	conv := Converter{imageFolder: "data/images/", log: logger}
+	conv.SetOptions(settings.MarkdownOptions{HardBreak: settings.HardBreakBackslash})
+	conv.RenderMarkdown(doc, &w)
+	assert.Equal(t, "a\\\nb\n", w.String())
+}
+
+func TestUIMacrosHtml(t *testing.T) {
+	conv := Converter{opts: settings.MarkdownOptions{UIMacros: settings.UIMacrosHtml}}
+	w := strings.Builder{}
+	conv.WriteKbd(&ast.Kbd{Keys: []string{"Ctrl", "<"}}, &w)
+	assert.Equal(t, "<kbd>Ctrl</kbd>+<kbd>&lt;</kbd>", w.String())
+	w.Reset()
+	conv.WriteMenu(&ast.Menu{Items: []string{"File", "Save As"}}, &w)
+	assert.Equal(t, `<span class="menuseq"><b class="menu">File</b>&#160;<b class="caret">&#8250;</b> <b class="menuitem">Save As</b></span>`, w.String())
+}
+
+func TestKeepComments(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	doc, err := parser.New("// line -- comment\ntext\n\n////\nblock\ncomment\n////", nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	w := strings.Builder{}
+	conv := Converter{log: logger}
+	conv.SetOptions(settings.MarkdownOptions{KeepComments: true})
+	conv.RenderMarkdown(doc, &w)
+	assert.Equal(t, "<!-- line - - comment -->\n\ntext\n\n<!--\nblock\ncomment\n-->\n", w.String())
+
+	//the comment isn't closed by the dashes of its text
+	w.Reset()
+	conv.WriteComment(&ast.Comment{Text: "arrow ---> and --- line"})
+	assert.Equal(t, "<!-- arrow - - -> and - - - line -->\n", w.String())
+}
+
+func TestUnknown(t *testing.T) {
+	w := strings.Builder{}
+	conv := Converter{}
+	conv.RenderMarkdown(&ast.Document{ContainerBlock: ast.ContainerBlock{Blocks: []ast.Block{
+		ast.NewParagraphFromStr("text"),
+		&ast.Unknown{Text: "|===\nno cells\n|===", Error: "parse table: null cell"},
+	}}}, &w)
+	assert.Equal(t, "text\n\n|===\nno cells\n|===\n", w.String())
+}
+
+func TestKeepAsciiMath(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	doc, err := parser.New("Inline stem:[a_1 * b]", nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	w := strings.Builder{}
+	conv := Converter{log: logger}
+	conv.SetOptions(settings.MarkdownOptions{KeepAsciiMath: true})
+	conv.RenderMarkdown(doc, &w)
+	assert.Equal(t, "Inline &#96;a\\_1 \\* b&#96;\n", w.String())
+}
+
+func TestDiagramImages(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	doc, err := parser.New("[plantuml, auth-flow, svg]\n----\nAlice -> Bob\n----\n\n[plantuml]\n----\nBob -> Alice\n----", nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	w := strings.Builder{}
+	conv := Converter{imageFolder: "images/", log: logger}
+	conv.SetOptions(settings.MarkdownOptions{DiagramImages: true})
+	conv.RenderMarkdown(doc, &w)
+	//diagram without the target name has no image to reference
+	assert.Equal(t, "![](images/auth-flow.svg)\n\n``` plantuml\nBob -> Alice\n```\n", w.String())
+}
+
+func TestFlavors(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	input := "[[intro]]\n== Intro\n\nWARNING: Use `{#x}` {carefully}\n\n[%collapsible]\n====\nHidden text\n===="
+	cases := []struct {
+		flavor string
+		exp    string
+	}{
+		{settings.FlavorMkDocs, "## Intro { #intro }\n\n!!! warning\n    Use `{\u2060#x}` {carefully}\n\n\n??? example\n    Hidden text\n"},
+		{settings.FlavorGFM, "## <a id=\"intro\"></a>Intro\n\n> [!WARNING]\n> Use `{#x}` {carefully}\n\n\n" +
+			"<details>\n<summary>Example</summary>\n\nHidden text\n\n</details>\n"},
+		{settings.FlavorCommonMark, "## <a id=\"intro\"></a>Intro\n\n> **Warning**\n>\n> Use `{#x}` {carefully}\n\n\n" +
+			"<details>\n<summary>Example</summary>\n\nHidden text\n\n</details>\n"},
+		{settings.FlavorHugo, "## Intro {#intro}\n\n{{% notice warning %}}\nUse `{#x}` {carefully}\n\n{{% /notice %}}\n\n\n" +
+			"{{% expand \"Example\" %}}\nHidden text\n\n{{% /expand %}}\n"},
+		{settings.FlavorDocusaurus, "## Intro {#intro}\n\n:::warning\nUse `{#x}` \\{carefully\\}\n\n:::\n\n\n" +
+			"<details>\n<summary>Example</summary>\n\nHidden text\n\n</details>\n"},
+	}
+	for _, c := range cases {
+		doc, err := parser.New(input, nil, logger).Parse("test.adoc")
+		if !assert.NoError(t, err) {
+			return
+		}
+		w := strings.Builder{}
+		conv := Converter{log: logger}
+		conv.SetOptions(settings.MarkdownOptions{Flavor: c.flavor})
+		conv.RenderMarkdown(doc, &w)
+		assert.Equal(t, c.exp, w.String(), c.flavor)
+	}
+}
+
+func TestFlavorBlockQuote(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	doc, err := parser.New("[TIP]\n====\nFirst\n\n* one\n* two\n====", nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	w := strings.Builder{}
+	conv := Converter{log: logger}
+	conv.SetOptions(settings.MarkdownOptions{Flavor: settings.FlavorGFM})
+	conv.RenderMarkdown(doc, &w)
+	//block quote markers are written on the empty lines too, otherwise the alert is split
+	assert.Equal(t, "> [!TIP]\n> First\n>\n>\n> * one\n>\n> * two\n", w.String())
+	//mkdocs keys syntax isn't supported by other flavors
+	w.Reset()
+	conv.WriteKbd(&ast.Kbd{Keys: []string{"Ctrl", "S"}}, &w)
+	assert.Equal(t, "<kbd>Ctrl</kbd>+<kbd>S</kbd>", w.String())
+}
+
+func TestFlavorByName(t *testing.T) {
+	f, err := FlavorByName("")
+	if assert.NoError(t, err) {
+		assert.Equal(t, settings.FlavorMkDocs, f.Name())
+	}
+	f, err = FlavorByName("GFM")
+	if assert.NoError(t, err) {
+		assert.Equal(t, settings.FlavorGFM, f.Name())
+	}
+	_, err = FlavorByName("asciidoc")
+	assert.Error(t, err)
+}
+
+func TestToc(t *testing.T) {
+	w := strings.Builder{}
+	conv := Converter{}
+	conv.writer = &w
+	conv.WriteToc(&ast.Toc{Title: "Contents", Entries: []*ast.TocEntry{
+		{Level: 2, Text: "Chapter 1", Url: "guide_1.md#chapter-1", Entries: []*ast.TocEntry{
+			{Level: 3, Text: "Section 1.1", Url: "guide_1.md#section-11"},
+			{Level: 3, Text: "Section 1.2", Url: "guide_1.md#section-12"},
+		}},
+		{Level: 2, Text: "Chapter 2", Url: "guide_2.md#chapter-2"},
+	}})
+	assert.Equal(t, `_Contents_
+
+* [Chapter 1](guide_1.md#chapter-1)
+    * [Section 1.1](guide_1.md#section-11)
+    * [Section 1.2](guide_1.md#section-12)
+* [Chapter 2](guide_2.md#chapter-2)
+`, w.String())
+}
+
+func TestFrontMatter(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	input := "= Guide\nJohn Doe <john@doe.com>\nv2.0, 2021-02-03\n:description: Some guide\n:keywords: one, two\n\n== Chapter 1\ntext"
+	doc, err := parser.New(input, nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	h := doc.Blocks[1].(*ast.Header)
+	w := strings.Builder{}
+	tmpl := settings.FrontMatter{
+		Hide: []string{"toc"},
+		Search: &settings.SearchOptions{Boost: 2},
+		Extra: map[string]interface{}{"template": "custom.html"},
+	}
+	assert.NoError(t, WriteFrontMatter(NewFrontMatter(doc, h, "test.adoc", nil, &tmpl), &w))
+	assert.Equal(t, `---
+title: Chapter 1
+description: Some guide
+authors:
+- John Doe
+date: "2021-02-03"
+tags:
+- one
+- two
+hide:
+- toc
+search:
+  boost: 2
+source: test.adoc:7
+template: custom.html
+---
+
+`, w.String())
+}
+
+func TestListMarkers(t *testing.T) {
+	assert.Equal(t, "z", alphaNumber(26))
+	assert.Equal(t, "ab", alphaNumber(28))
+	assert.Equal(t, "MCMXCIV", romanNumber(1994))
+	assert.Equal(t, "iv. ", listMarker(&ast.List{Numbered: true, Style: "lowerroman", Start: 4}, 0))
+	assert.Equal(t, "5. ", listMarker(&ast.List{Numbered: true, Start: 4, Reversed: true}, 1))
+	assert.Equal(t, "2. ", listMarker(&ast.List{Numbered: true, Start: -1}, 1))
+	assert.Equal(t, "b. ", listMarker(&ast.List{Numbered: true, Style: "loweralpha", Start: -3}, 1))
+}
+
 func TestEscapeHtml(t *testing.T) {
 	assert.Equal(t, "`это` ка&lt;кие&gt;-то `неправильные` пчелы `и они`&lt;&gt;", utils.FixFormatting("`это` ка<кие>-то `неправильные` пчелы `и они`<>"))
 }
@@ -141,7 +536,7 @@ func TestDbg(t *testing.T) {
 
 func TestFixString(t *testing.T) {
 	assert.Equal(t, "bc de", fixString("*bc de*", true))
-	assert.Equal(t, "[x] abcd", fixString("[*] abcd", false))
+	assert.Equal(t, "[*] abcd", fixString("[*] abcd", false))
 	assert.Equal(t, "some **bold** text and mi**dd**le and *)", fixString("some *bold* text and mi**dd**le and *)", false))
 	assert.Equal(t, "`#abc_id` \\# de", fixString("#abc_id # de", false))
 	assert.Equal(t, `&lt;&gt;`, fixString("<>", false))
@@ -2,13 +2,14 @@ package parser
 
 import (
 	"asciidoc2md/ast"
+	"asciidoc2md/diag"
 	"asciidoc2md/lexer"
+	"asciidoc2md/subs"
 	"asciidoc2md/token"
 	"asciidoc2md/utils"
 	"cdr.dev/slog"
 	"context"
 	"errors"
-	"fmt"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -27,6 +28,12 @@ type Parser struct{
 	curBlock ast.Block //block which is being parsed
 	log slog.Logger
 	tableFlag bool
+	attrs map[string]string //document attributes, shared with included documents
+	include bool //included document parser, included documents have no document header
+	lines []string //input lines
+	header *ast.DocumentHeader
+	file string //source file name used in the node positions and the error messages
+	diags *diag.Collector //parse errors are recorded here and parsing continues, nil means stop at the first error
 }
 
 type IncludeFunc func(name string) ([]byte,error)
@@ -36,9 +43,17 @@ func New(input string, f IncludeFunc, logger slog.Logger) *Parser {
 	p.log = logger
 	p.f = f
 	p.l = lexer.New(input)
+	p.attrs = make(map[string]string)
+	p.lines = strings.Split(input, "\n")
 	return &p
 }
 
+// SetDiagnostics enables error recovery: parse errors are recorded by the collector, the failed region
+// becomes ast.Unknown node and parsing continues
+func (p *Parser) SetDiagnostics(c *diag.Collector) {
+	p.diags = c
+}
+
 func (p *Parser) advance() bool {
 	return p.advanceInternal(true)
 }
@@ -93,7 +108,7 @@ func (p *Parser) readAll() {
 	for tok != nil {
 		if tok.Type == token.EOF && prev.Type != token.NEWLINE {
 			//add newline at the end of file to simplify parsing
-			p.tokens = append(p.tokens, &token.Token{token.NEWLINE,"\n",tok.Line})
+			p.tokens = append(p.tokens, &token.Token{Type: token.NEWLINE, Literal: "\n", Line: tok.Line, Col: tok.Col, EndLine: tok.Line, EndCol: tok.Col})
 		}
 		p.tokens = append(p.tokens, tok)
 		prev = tok
@@ -105,16 +120,25 @@ func (p *Parser) Parse(name string) (*ast.Document, error) {
 	var doc ast.Document
 	//use only file name without directory
 	_, doc.Name = filepath.Split(name)
+	p.file = name
 	p.readAll()
+	if len(p.tokens) > 0 {
+		doc.Pos = p.pos(p.tokens[0], p.tokens[len(p.tokens)-1])
+	}
 
 forLoop:
 	for p.advanceInternal(false) {
+		from := p.tok
 		switch {
 		case p.tok.Type == token.EOF:
 			break forLoop
 		case p.isListMarker():
 			l, err := p.parseList(nil)
 			if err != nil {
+				if u, ok := p.recoverFrom(from, err); ok {
+					doc.Add(u)
+					continue
+				}
 				return nil, err
 			}
 			doc.Add(l)
@@ -123,6 +147,10 @@ forLoop:
 		default:
 			b, err := p.parseBlock()
 			if err != nil {
+				if u, ok := p.recoverFrom(from, err); ok {
+					doc.Add(u)
+					continue
+				}
 				return nil, err
 			}
 
@@ -131,51 +159,226 @@ forLoop:
 			}
 		}
 	}
+	doc.Attributes = p.attrs
+	doc.Header = p.header
+	if !p.include {
+		//included documents are numbered as a part of the including document
+		numberSections(&doc)
+	}
 	return &doc, nil
 }
 
+var attrEntryRE = regexp.MustCompile(`^:(!?)(\w[\w-]*)(!?):(?:[ \t]+(.*?))?[ \t]*$`)
+
+// parseAttrEntry stores ":name: value" document attribute, ":name!:" and ":!name:" unset the attribute.
+func (p *Parser) parseAttrEntry() error {
+	matches := attrEntryRE.FindStringSubmatch(p.tok.Literal)
+	if len(matches) != 5 {
+		return p.errorf("invalid attribute entry: %v", p.tok)
+	}
+	if matches[1] != "" || matches[3] != "" {
+		delete(p.attrs, matches[2])
+	} else {
+		p.attrs[matches[2]] = matches[4]
+	}
+	if !p.advance() {
+		return p.cannotAdvance()
+	}
+	return nil
+}
+
+// isDelimiter checks if the token starts a new block, parsing is resumed there after an error
+func isDelimiter(typ token.TokenType) bool {
+	switch typ {
+	case token.HEADER, token.EX_BLOCK, token.SIDEBAR, token.QUOTE_BLOCK, token.TABLE, token.L_BOUNDARY,
+		token.SYNTAX_BLOCK, token.FENCED_SYNTAX_BLOCK, token.PASS_BLOCK, token.COMMENT_BLOCK, token.BLOCK_OPTS:
+		return true
+	}
+	return false
+}
+
+// recoverFrom records the error and skips the tokens starting from the from token up to the next blank line or
+// block delimiter. If the failed block starts with a delimiter, the tokens are skipped up to the closing delimiter.
+// The skipped region is returned as ast.Unknown node, the current token is the last skipped token.
+// False is returned if there is no diagnostics collector.
+func (p *Parser) recoverFrom(from *token.Token, err error) (*ast.Unknown, bool) {
+	start := -1
+	for i, tok := range p.tokens {
+		if tok == from {
+			start = i
+			break
+		}
+	}
+	if p.diags == nil || start == -1 || from.Type == token.EOF {
+		return nil, false
+	}
+	p.diags.Report(err, p.pos(from, from))
+	p.log.Debug(context.Background(), "skipping source after parse error", slog.Error(err))
+
+	closing := token.TokenType(token.ILLEGAL)
+	switch from.Type {
+	case token.EX_BLOCK, token.SIDEBAR, token.QUOTE_BLOCK, token.TABLE, token.L_BOUNDARY:
+		closing = from.Type
+	}
+	end := start //last skipped token
+	for i := start + 1; i < len(p.tokens) && p.tokens[i].Type != token.EOF; i++ {
+		tok, prev := p.tokens[i], p.tokens[i-1]
+		if closing != token.ILLEGAL {
+			end = i
+			if tok.Type == closing {
+				break
+			}
+			continue
+		}
+		if prev.Type == token.NEWLINE && (tok.Type == token.NEWLINE || isDelimiter(tok.Type)) {
+			break
+		}
+		end = i
+	}
+	last := from
+	for _, tok := range p.tokens[start : end+1] {
+		if tok.Type != token.NEWLINE && tok.Type != token.INDENT {
+			last = tok
+		}
+	}
+	var lines []string
+	for l := from.Line; l <= last.EndLine && int(l) <= len(p.lines); l++ {
+		lines = append(lines, strings.TrimRight(p.lines[l-1], "\r"))
+	}
+	u := &ast.Unknown{Pos: p.pos(from, last), Text: strings.Join(lines, "\n"), Error: err.Error()}
+	p.next = end + 1
+	p.tok = p.tokens[end]
+	if end > 0 {
+		p.prevTok = p.tokens[end-1]
+	}
+	return u, true
+}
+
+// hasAttr checks if the document attribute is set.
+func (p *Parser) hasAttr(name string) bool {
+	_, ok := p.attrs[name]
+	return ok
+}
+
 var ErrCannotAdvance = errors.New("cannot advance tokens")
 
-func (p *Parser) parseBlock() (ast.Block, error) {
+// errorf formats the error prefixed with the position of the current token: "file.adoc:12:5: message"
+func (p *Parser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.tok, format, args...)
+}
+
+// errorAt formats the error prefixed with the position of the token
+func (p *Parser) errorAt(tok *token.Token, format string, args ...interface{}) error {
+	pos := ast.Pos{File: p.file}
+	if tok != nil {
+		pos.Line, pos.Col, pos.EndLine, pos.EndCol = tok.Line, tok.Col, tok.EndLine, tok.EndCol
+	}
+	return diag.Errorf(pos, format, args...)
+}
+
+// cannotAdvance returns ErrCannotAdvance with the current position
+func (p *Parser) cannotAdvance() error {
+	return p.errorf("%w", ErrCannotAdvance)
+}
+
+// pos returns the source range from the first char of the from token to the last char of the to token
+func (p *Parser) pos(from, to *token.Token) ast.Pos {
+	return ast.Pos{File: p.file, Line: from.Line, Col: from.Col, EndLine: to.EndLine, EndCol: to.EndCol}
+}
+
+// setPos sets the node range unless it's already set by the nested parse function
+func (p *Parser) setPos(b ast.Block, from, to *token.Token) {
+	if b == nil || utils.IsNil(b) || from == nil {
+		return
+	}
+	if pos, ok := b.(ast.Positioned); ok && pos.Position().Line == 0 {
+		*pos.Position() = p.pos(from, to)
+	}
+}
+
+// setRange sets the node range from the from token to the last consumed token
+func (p *Parser) setRange(b ast.Block, from *token.Token) {
+	p.setPos(b, from, p.lastTok(from))
+}
+
+// lastTok returns the last consumed token which isn't a trailing newline or indent,
+// the from token is returned if nothing is consumed after it
+func (p *Parser) lastTok(from *token.Token) *token.Token {
+	for i := p.next - 2; i >= 0 && i < len(p.tokens); i-- {
+		tok := p.tokens[i]
+		if tok.Line < from.Line || tok.Line == from.Line && tok.Col < from.Col {
+			break
+		}
+		if tok.Type != token.NEWLINE && tok.Type != token.INDENT {
+			return tok
+		}
+	}
+	return from
+}
+
+func (p *Parser) parseBlock() (b ast.Block, err error) {
 	var options string
-	ctx := context.Background()
+	from := p.tok
+	defer func() {
+		if err == nil {
+			p.setRange(b, from)
+		}
+	}()
 
 	if p.tok.Type == token.BLOCK_OPTS {
 		options = p.tok.Literal
 		//p.log.Debug(context.Background(), "parseBlock: BLOCK_OPTS", slog.F("token", p.tok))
 		//skip to the token after newline
 		if !p.advanceMany(2) {
-			return nil, fmt.Errorf("cannot skip newline: unexpected EOF")
+			return nil, p.errorf("cannot skip newline: unexpected EOF")
 		}
 	}
 
 
 	switch {
-	case p.tok.Type == token.COMMENT:
-		//ignore comment
-		p.log.Warn(ctx, "ignore comment line", slog.F("token", p.tok))
-		if !p.advance() { return nil, ErrCannotAdvance }
-		return nil, nil
+	case p.tok.Type == token.COMMENT || p.tok.Type == token.COMMENT_BLOCK:
+		return p.parseComment()
 	case p.tok.Type == token.L_BOUNDARY:
 		return p.parseListBlock(p.tok)
 	case p.isListMarker():
-		return p.parseList(nil)
+		l, err := p.parseList(nil)
+		if err != nil {
+			return nil, err
+		}
+		l.SetOptions(options)
+		return l, nil
 	case p.tok.Type == token.BLOCK_TITLE:
 		t := ast.BlockTitle{Title: p.tok.Literal}
-		if !p.advance() { return nil, ErrCannotAdvance }
+		if !p.advance() { return nil, p.cannotAdvance() }
 		return &t, nil
 	case p.tok.Type == token.HEADER:
 		return p.parseHeader("", options)
+	case p.tok.Type == token.ATTR_ENTRY:
+		return nil, p.parseAttrEntry()
 	case p.isParagraph(p.tok):
 		//paragraph
-		return p.parseParagraph()
+		attrs := ast.ParseAttributes(options)
+		par, err := p.parseParagraph(attrs.Has("hardbreaks"))
+		if err != nil {
+			return nil, err
+		}
+		par.NoReplacements = !subs.Enabled(attrs.Get("subs"), "replacements")
+		return par, nil
+	case p.tok.Type == token.TOC:
+		toc := &ast.Toc{Title: p.attrs["toc-title"]}
+		if !p.advance() {
+			return nil, p.cannotAdvance()
+		}
+		return toc, nil
 	case p.tok.Type == token.BLOCK_IMAGE:
 		return p.parseImage(options)
+	case p.tok.Type == token.BLOCK_VIDEO || p.tok.Type == token.BLOCK_AUDIO:
+		return p.parseMedia()
 	case p.tok.Type == token.INCLUDE:
 		return p.parseInclude(options)
 	case p.tok.Type == token.HOR_LINE:
 		if !p.advance() {
-			return nil, fmt.Errorf("cannot advance after HOR_LINE token")
+			return nil, p.errorf("cannot advance after HOR_LINE token")
 		}
 		return &ast.HorLine{}, nil
 	case p.tok.Type == token.ADMONITION:
@@ -193,23 +396,60 @@ func (p *Parser) parseBlock() (ast.Block, error) {
 		p.advance()
 		return sb, nil
 	case p.tok.Type == token.SYNTAX_BLOCK:
+		if d := ast.NewDiagramBlock(options, p.tok.Literal); d != nil {
+			p.advance()
+			return d, nil
+		}
 		sb := &ast.SyntaxBlock{Literal: p.tok.Literal}
 		sb.SetOptions(options)
 		p.advance()
 		return sb, nil
+	case p.tok.Type == token.PASS_BLOCK:
+		var b ast.Block = &ast.PassBlock{Literal: p.tok.Literal}
+		switch style := ast.ParseAttributes(options).Style; style {
+		case "stem", ast.LatexMath, ast.AsciiMath:
+			b = &ast.Stem{Notation: p.stemNotation(style), Text: strings.TrimSpace(p.tok.Literal)}
+		}
+		p.advance()
+		return b, nil
 	case p.tok.Type == token.BOOKMARK:
 		return p.parseBookmark()
 	case p.tok.Type == token.INDENT || p.tok.Type == token.CONCAT_PAR:
 		//skip it for now
 		if !p.advance() {
-			return nil, ErrCannotAdvance
+			return nil, p.cannotAdvance()
 		}
 		return nil, nil
 	}
-	return nil, fmt.Errorf("parse block: unknown token %v", p.tok)
+	return nil, p.errorf("parse block: unknown token %v", p.tok)
 	//return nil, nil
 }
 
+// parseComment reads comment block or consecutive comment lines
+func (p *Parser) parseComment() (*ast.Comment, error) {
+	if p.tok.Type == token.COMMENT_BLOCK {
+		c := &ast.Comment{Text: strings.TrimRight(p.tok.Literal, "\r\n"), Block: true}
+		if !p.advance() {
+			return nil, p.cannotAdvance()
+		}
+		return c, nil
+	}
+	var lines []string
+	for {
+		lines = append(lines, strings.TrimSpace(strings.TrimPrefix(p.tok.Literal, "//")))
+		if !p.advance() {
+			return nil, p.cannotAdvance()
+		}
+		next := p.peekToken(1)
+		if p.tok.Type != token.NEWLINE || next == nil || next.Type != token.COMMENT {
+			break
+		}
+		//skip newline
+		p.advance()
+	}
+	return &ast.Comment{Text: strings.Join(lines, "\n")}, nil
+}
+
 func (p *Parser) isDoubleNewline() bool {
 	return p.tok.Type == token.NEWLINE && (p.prevTok.Type == token.NEWLINE || p.prevTok.Type == token.INDENT)
 }
@@ -223,7 +463,9 @@ func (p *Parser) isColumn() bool {
 }
 
 func (p *Parser) isParagraph(tok *token.Token) bool {
-	return tok.Type == token.STR || tok.Type == token.INLINE_IMAGE || tok.Type == token.URL || tok.Type == token.INT_LINK
+	return tok.Type == token.STR || tok.Type == token.INLINE_IMAGE || tok.Type == token.URL || tok.Type == token.INT_LINK ||
+		tok.Type == token.INDEX_TERM || tok.Type == token.KBD || tok.Type == token.BTN || tok.Type == token.MENU ||
+		tok.Type == token.BIB_ANCHOR || tok.Type == token.STEM
 }
 
 func (p *Parser) isParagraphEnd() bool {
@@ -233,55 +475,223 @@ func (p *Parser) isParagraphEnd() bool {
 		return false
 	}*/
 
-	return !p.isParagraph(p.tok)
+	//bookmark inside the paragraph line is an inline anchor
+	return !p.isParagraph(p.tok) && p.tok.Type != token.BOOKMARK
+}
+
+// newBookmark creates a bookmark from "id" or "id,reftext" literal.
+func newBookmark(literal string) *ast.Bookmark {
+	parts := strings.SplitN(literal, ",", 2)
+	b := &ast.Bookmark{Literal: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 {
+		b.RefText = strings.TrimSpace(parts[1])
+	}
+	return b
+}
+
+// newBibAnchor creates a bibliography anchor from "ref,label" literal, the reference is the label by default
+func newBibAnchor(literal string) *ast.BibAnchor {
+	parts := strings.SplitN(literal, ",", 2)
+	b := &ast.BibAnchor{Id: strings.TrimSpace(parts[0]), Label: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+		b.Label = strings.TrimSpace(parts[1])
+	}
+	return b
+}
+
+// stemNotation resolves "stem" style to the notation set by ":stem:" document attribute, AsciiMath is the default
+func (p *Parser) stemNotation(style string) string {
+	if style == "stem" {
+		style = p.attrs["stem"]
+	}
+	if style == ast.LatexMath {
+		return ast.LatexMath
+	}
+	return ast.AsciiMath
+}
+
+// newInlineStem parses "stem:[...]" macro, "\]" inside the formula is an escaped closing bracket
+func (p *Parser) newInlineStem(literal string) *ast.InlineStem {
+	colon := strings.Index(literal, ":")
+	text := literal[colon+2 : len(literal)-1]
+	return &ast.InlineStem{Notation: p.stemNotation(literal[:colon]), Text: strings.ReplaceAll(text, `\]`, "]")}
 }
 
 func (p *Parser) parseBookmark() (ast.Block, error) {
-	b := &ast.Bookmark{Literal: p.tok.Literal}
+	b := newBookmark(p.tok.Literal)
 	//check if it is an Id of a header
 	if !p.advance() {
-		return nil, ErrCannotAdvance
+		return nil, p.cannotAdvance()
 	}
 	if p.tok.Type == token.NEWLINE && p.peekToken(1).Type == token.HEADER {
 		if !p.advance() {
-			return nil, ErrCannotAdvance
+			return nil, p.cannotAdvance()
 		}
 		h, err := p.parseHeader(b.Literal, "")
-		return h, err
+		if err != nil {
+			return nil, err
+		}
+		if b.RefText != "" {
+			h.RefText = b.RefText
+		}
+		return h, nil
 	}
 	return b, nil
 
 }
 
+var uiMacroRE = regexp.MustCompile(`^(kbd|btn|menu):([^\[]*)\[(.*)\]$`)
+
+// parseUIMacro parses "kbd:[Ctrl+S]", "btn:[Save]" and "menu:File[Save As > Copy]" macros.
+// The macros are only enabled by ":experimental:" attribute, otherwise they are left as plain text.
+func (p *Parser) parseUIMacro() (ast.Block, error) {
+	matches := uiMacroRE.FindStringSubmatch(p.tok.Literal)
+	if len(matches) != 4 {
+		return nil, p.errorf("invalid UI macro: %v", p.tok)
+	}
+	var b ast.Block
+	switch {
+	case !p.hasAttr("experimental"):
+		b = &ast.Text{Text: p.tok.Literal}
+	case matches[1] == "kbd":
+		b = &ast.Kbd{Keys: splitKeys(strings.ReplaceAll(matches[3], `\]`, "]"))}
+	case matches[1] == "btn":
+		b = &ast.Button{Text: strings.TrimSpace(matches[3])}
+	default:
+		m := &ast.Menu{Items: []string{strings.TrimSpace(matches[2])}}
+		for _, item := range strings.Split(matches[3], ">") {
+			if item = strings.TrimSpace(item); item != "" {
+				m.Items = append(m.Items, item)
+			}
+		}
+		b = m
+	}
+	if !p.advance() {
+		return nil, p.cannotAdvance()
+	}
+	return b, nil
+}
+
+// splitKeys splits "Ctrl+Shift+S", "Ctrl++" or "Ctrl,T" keys combination
+func splitKeys(s string) []string {
+	if strings.TrimSpace(s) == "+" {
+		return []string{"+"}
+	}
+	sep := "+"
+	if strings.Contains(s, ",") {
+		sep = ","
+	}
+	var plus bool
+	if sep == "+" && strings.HasSuffix(s, "++") {
+		//"Ctrl++" means Ctrl and plus keys
+		plus = true
+		s = s[:len(s)-2]
+	}
+	var keys []string
+	for _, k := range strings.Split(s, sep) {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if plus {
+		keys = append(keys, "+")
+	}
+	return keys
+}
+
+var indexTermRE = regexp.MustCompile(`^(?:\({3}(.+)\){3}|\({2}(.+)\){2}|indexterm:\[(.*)\]|indexterm2:\[(.*)\])$`)
+
+// parseIndexTerm parses visible "((term))", "indexterm2:[term]" and
+// concealed "(((primary, secondary, tertiary)))", "indexterm:[primary, secondary, tertiary]" index terms.
+// An empty term like "((  ))" is left as plain text.
+func (p *Parser) parseIndexTerm() (ast.Block, error) {
+	matches := indexTermRE.FindStringSubmatch(p.tok.Literal)
+	if len(matches) != 5 {
+		return nil, p.errorf("invalid index term: %v", p.tok)
+	}
+	var t ast.IndexTerm
+	var terms string
+	switch {
+	case matches[1] != "":
+		terms = matches[1]
+	case matches[2] != "":
+		t.Visible = true
+		t.Terms = []string{matches[2]}
+	case matches[4] != "":
+		t.Visible = true
+		t.Terms = []string{matches[4]}
+	default:
+		terms = matches[3]
+	}
+	if !t.Visible {
+		for _, term := range strings.Split(terms, ",") {
+			term = strings.Trim(strings.TrimSpace(term), `"`)
+			if term != "" {
+				t.Terms = append(t.Terms, term)
+			}
+		}
+	}
+	var b ast.Block = &t
+	if len(t.Terms) == 0 || strings.TrimSpace(t.Terms[0]) == "" {
+		b = &ast.Text{Text: p.tok.Literal}
+	}
+	if !p.advance() {
+		return nil, p.cannotAdvance()
+	}
+	return b, nil
+}
+
 func (p *Parser) parseInternalLink() (*ast.Link, error) {
 	link := ast.Link{Internal: true}
 
 	parts := strings.SplitN(p.tok.Literal, ",", 2)
 	if len(parts) == 0 {
-		return nil, fmt.Errorf("invalid internal link: %v", p.tok.Literal)
+		return nil, p.errorf("invalid internal link: %v", p.tok.Literal)
 	}
 	link.Url = parts[0]
 	if len(parts) == 2 {
 		link.Text = strings.TrimSpace(parts[1])
 	}
 	if !p.advance() {
-		return nil, ErrCannotAdvance
+		return nil, p.cannotAdvance()
 	}
 	return &link, nil
 }
 
+// setLinkAttributes parses "text", "text^" and "\"text, with comma\", window=_blank, role=ext" link attributes.
+// The text is parsed as the attribute list only if it has named attributes.
+func setLinkAttributes(link *ast.Link, literal string) {
+	text := strings.TrimSpace(literal)
+	if strings.Contains(text, "=") {
+		attrs := ast.ParseAttributes(text)
+		text = attrs.Pos(0)
+		link.Window = attrs.Get("window")
+		link.Roles = strings.Fields(attrs.Get("role"))
+	}
+	if strings.HasSuffix(text, "^") {
+		//new window shorthand
+		text = strings.TrimSuffix(text, "^")
+		link.Window = "_blank"
+	}
+	link.Text = text
+}
+
 func (p *Parser) parseLink() (*ast.Link, error) {
 	link := ast.Link{Url: p.tok.Literal}
 
 	if !p.advance() {
-		return nil, ErrCannotAdvance
+		return nil, p.cannotAdvance()
 	}
 	if p.tok.Type == token.LINK_NAME {
-		link.Text = strings.TrimSpace(p.tok.Literal)
+		setLinkAttributes(&link, p.tok.Literal)
 		if !p.advance() {
-			return nil, ErrCannotAdvance
+			return nil, p.cannotAdvance()
 		}
 	}
+	if link.Text == "" && strings.HasPrefix(link.Url, "mailto:") {
+		//email address is the link text
+		link.Text = strings.TrimPrefix(link.Url, "mailto:")
+	}
 	return &link, nil
 }
 
@@ -292,9 +702,16 @@ func (p *Parser) parseBlockBody(delim *token.Token) (*ast.ContainerBlock, error)
 		if p.tok.Type == token.NEWLINE {
 			p.advance()
 		} else {
+			from := p.tok
 			b, err := p.parseBlock()
 			if err != nil {
-				return nil, err
+				u, ok := p.recoverFrom(from, err)
+				if !ok {
+					return nil, err
+				}
+				cb.Add(u)
+				p.advance()
+				continue
 			}
 			if b != nil {
 				cb.Add(b)
@@ -317,7 +734,7 @@ func (p *Parser) parseExampleBlock(options string, delim *token.Token) (*ast.Exa
 
 	//skip delimiter + newline tokens
 	if !p.advanceMany(2) {
-		return nil, fmt.Errorf("parse example block: cannot advance tokens")
+		return nil, p.errorf("parse example block: cannot advance tokens")
 	}
 
 	cb, err := p.parseBlockBody(delim)
@@ -333,13 +750,16 @@ func (p *Parser) parseAdmonition() (*ast.Admonition, error) {
 	admonition.Kind = p.tok.Literal
 	admonition.Content = &ast.ContainerBlock{}
 	if !p.advance() {
-		return nil, fmt.Errorf("parse admonition error: cannot advance tokens")
+		return nil, p.errorf("parse admonition error: cannot advance tokens")
 	}
-	b, err := p.parseParagraph()
+	from := p.tok
+	b, err := p.parseParagraph(false)
 	if err != nil {
 			return nil, err
 		}
+	p.setRange(b, from)
 	admonition.Content.Add(b)
+	p.setRange(admonition.Content, from)
 
 	return &admonition, nil
 }
@@ -357,12 +777,16 @@ func (p *Parser) parseHeader(id string, options string) (*ast.Header, error) {
 	}
 	h.Id = id
 	h.Options = options
-	if strings.Contains(h.Options,"float") {
+	attrs := ast.ParseAttributes(options)
+	h.RefText = attrs.Get("reftext")
+	h.Style = attrs.Style
+	if strings.Contains(h.Options,"float") || strings.Contains(h.Options, "discrete") {
 		h.Float = true //not a header, just formatted like a header text
 	}
 	h.Level = len(p.tok.Literal)
+	from := p.tok
 	if !p.advance() {
-		return nil, fmt.Errorf("parseHeader: cannot advance")
+		return nil, p.errorf("parseHeader: cannot advance")
 	}
 	if p.tok.Type == token.STR {
 		//remove trailing "...==="
@@ -370,18 +794,132 @@ func (p *Parser) parseHeader(id string, options string) (*ast.Header, error) {
 		//p.log.Debug(context.Background(), "parseHeader", slog.F("token", p.tok))
 
 		if !p.advance() {
-			return nil, fmt.Errorf("parseHeader: cannot advance")
+			return nil, p.errorf("parseHeader: cannot advance")
+		}
+		p.setRange(&h, from)
+		if h.Level == 1 && !h.Float && !p.include && p.header == nil {
+			//document title
+			if err := p.parseDocumentHeader(&h); err != nil {
+				return nil, err
+			}
 		}
 
 		return &h, nil
 	}
 
-	return nil, fmt.Errorf("invalid header text token: %v", p.tok)
+	return nil, p.errorf("invalid header text token: %v", p.tok)
 }
 
-func (p *Parser) parseParagraph() (*ast.Paragraph, error) {
+// "Kismet R. Lee <kismet@asciidoctor.org>"
+var authorRE = regexp.MustCompile(`^([\pL\pN_][\pL\pN_\-'.]*)(?: +([\pL\pN_][\pL\pN_\-'.]*))?(?: +([\pL\pN_][\pL\pN_\-'.]*))?(?: +<([^>]+)>)?$`)
+// "v1.0, 2019-01-01: Remark", "v1.0", "2019-01-01", "1.0, October 2, 2013"
+var revisionRE = regexp.MustCompile(`^(?:(v)?(\d[\w.]*)(?:, *(` + dateRE + `))?|(` + dateRE + `))(?: *: *(.*))?$`)
+
+// "2019-01-01", "01.02.2019", "October 2, 2013"
+const dateRE = `\d{1,4}[-./]\d{1,2}[-./]\d{1,4}|\pL+ \d{1,2}, \d{4}`
+
+// parseDocumentHeader reads author line, revision line and attribute entries right after the document title
+func (p *Parser) parseDocumentHeader(h *ast.Header) error {
+	header := &ast.DocumentHeader{Title: h.Text, Attributes: make(map[string]string)}
+	if i := strings.LastIndex(h.Text, ": "); i != -1 {
+		header.Title = h.Text[:i]
+		header.Subtitle = strings.TrimSpace(h.Text[i+2:])
+	}
+	p.header = header
+	for line := 0; p.tok.Type == token.NEWLINE; line++ {
+		next := p.peekToken(1)
+		if next == nil || next.Type == token.NEWLINE || next.Type == token.EOF {
+			//header ends at the blank line
+			return nil
+		}
+		if next.Type == token.ATTR_ENTRY {
+			p.advance()
+			if err := p.parseAttrEntry(); err != nil {
+				return err
+			}
+			matches := attrEntryRE.FindStringSubmatch(next.Literal)
+			if matches[1] == "" && matches[3] == "" {
+				header.Attributes[matches[2]] = matches[4]
+			}
+			continue
+		}
+		if next.Type == token.COMMENT {
+			p.advance()
+			p.advance()
+			continue
+		}
+		raw := strings.TrimSpace(p.rawLine(next))
+		switch {
+		case line == 0 && len(header.Authors) == 0 && parseAuthors(raw, header):
+		case line == 1 && len(header.Authors) > 0 && parseRevision(raw, header):
+		default:
+			//not a header line
+			return nil
+		}
+		//skip the line
+		p.advance()
+		for p.tok.Type != token.NEWLINE && p.tok.Type != token.EOF {
+			if !p.advance() {
+				return p.cannotAdvance()
+			}
+		}
+	}
+	return nil
+}
+
+// rawLine returns the input line the token is on
+func (p *Parser) rawLine(tok *token.Token) string {
+	if tok.Line == 0 || int(tok.Line) > len(p.lines) {
+		return tok.Literal
+	}
+	return p.lines[tok.Line-1]
+}
+
+// parseAuthors parses "Name1 <email1>; Name2 <email2>" author line
+func parseAuthors(line string, header *ast.DocumentHeader) bool {
+	var authors []ast.Author
+	for _, a := range strings.Split(line, ";") {
+		matches := authorRE.FindStringSubmatch(strings.TrimSpace(a))
+		if len(matches) != 5 {
+			return false
+		}
+		var names []string
+		for _, n := range matches[1:4] {
+			if n != "" {
+				names = append(names, strings.ReplaceAll(n, "_", " "))
+			}
+		}
+		authors = append(authors, ast.Author{Name: strings.Join(names, " "), Email: matches[4]})
+	}
+	header.Authors = authors
+	return true
+}
+
+// parseRevision parses "v1.0, 2019-01-01: Remark" revision line.
+// The line is a revision number prefixed with "v" and/or a date, so an ordinary text line isn't taken for it.
+func parseRevision(line string, header *ast.DocumentHeader) bool {
+	matches := revisionRE.FindStringSubmatch(line)
+	if len(matches) != 6 {
+		return false
+	}
+	number, date := matches[2], matches[3]+matches[4]
+	if matches[1] == "" && date == "" {
+		//"1.0" alone could be a text
+		return false
+	}
+	header.Revision = ast.Revision{Number: number, Date: date, Remark: matches[5]}
+	return true
+}
+
+var hardBreakRE = regexp.MustCompile(`(^|\s+)\+\s*$`)
+
+// parseParagraph reads paragraph text. When hardBreaks is set (or ":hardbreaks-option:" document attribute is set),
+// every line break is preserved.
+func (p *Parser) parseParagraph(hardBreaks bool) (*ast.Paragraph, error) {
 	var par ast.Paragraph
+	hardBreaks = hardBreaks || p.hasAttr("hardbreaks-option") || p.hasAttr("hardbreaks")
 	for {
+		from, n := p.tok, len(par.Blocks)
 		switch {
 		case p.tok.Type == token.URL:
 			link, err := p.parseLink()
@@ -390,7 +928,16 @@ func (p *Parser) parseParagraph() (*ast.Paragraph, error) {
 			}
 			par.Add(link)
 		case p.tok.Type == token.STR:
-			par.Add(&ast.Text{Text: p.tok.Literal})
+			next := p.peekToken(1)
+			if (next == nil || next.Type == token.NEWLINE) && hardBreakRE.MatchString(p.tok.Literal) {
+				// "line +" explicit line break
+				if text := hardBreakRE.ReplaceAllString(p.tok.Literal, ""); text != "" {
+					par.Add(&ast.Text{Text: text})
+				}
+				par.Add(&ast.LineBreak{})
+			} else {
+				par.Add(&ast.Text{Text: p.tok.Literal})
+			}
 			p.advance()
 		case p.tok.Type == token.INLINE_IMAGE:
 			im, err := p.parseInlineImage()
@@ -404,10 +951,47 @@ func (p *Parser) parseParagraph() (*ast.Paragraph, error) {
 				return nil, err
 			}
 			par.Add(link)
+		case p.tok.Type == token.INDEX_TERM:
+			term, err := p.parseIndexTerm()
+			if err != nil {
+				return nil, err
+			}
+			par.Add(term)
+		case p.tok.Type == token.KBD || p.tok.Type == token.BTN || p.tok.Type == token.MENU:
+			b, err := p.parseUIMacro()
+			if err != nil {
+				return nil, err
+			}
+			par.Add(b)
+		case p.tok.Type == token.BOOKMARK:
+			//inline anchor
+			par.Add(newBookmark(p.tok.Literal))
+			p.advance()
+		case p.tok.Type == token.BIB_ANCHOR:
+			par.Add(newBibAnchor(p.tok.Literal))
+			p.advance()
+		case p.tok.Type == token.STEM:
+			par.Add(p.newInlineStem(p.tok.Literal))
+			p.advance()
+		}
+		for _, b := range par.Blocks[n:] {
+			p.setRange(b, from)
 		}
 		if p.tok.Type == token.NEWLINE && p.isParagraph(p.peekToken(1)) {
-			//single line break works as a space
-			par.Add(&ast.Text{Text: "\n"})
+			from, n = p.tok, len(par.Blocks)
+			_, isBreak := par.Blocks[len(par.Blocks)-1].(*ast.LineBreak)
+			switch {
+			case isBreak:
+				//line break is already there
+			case hardBreaks:
+				par.Add(&ast.LineBreak{})
+			default:
+				//single line break works as a space
+				par.Add(&ast.Text{Text: "\n"})
+			}
+			for _, b := range par.Blocks[n:] {
+				p.setPos(b, from, from)
+			}
 			p.advance()
 		}
 		// read until double NEWLINE or list marker (which means we're inside the list) or "+" paragraph concatenation
@@ -415,6 +999,10 @@ func (p *Parser) parseParagraph() (*ast.Paragraph, error) {
 			break
 		}
 	}
+	//line break at the end of the paragraph makes no sense
+	if _, ok := par.Blocks[len(par.Blocks)-1].(*ast.LineBreak); ok {
+		par.Blocks = par.Blocks[:len(par.Blocks)-1]
+	}
 	return &par, nil
 }
 
@@ -425,31 +1013,48 @@ var inlineImageRE = regexp.MustCompile(`^image:(.*)\[`)
 func (p *Parser) parseImage(options string) (*ast.Image, error) {
 	matches := imageRE.FindStringSubmatch(p.tok.Literal)
 	if len(matches) != 2 {
-		return nil, fmt.Errorf("invalid image literal: %v", p.tok.Literal)
+		return nil, p.errorf("invalid image literal: %v", p.tok.Literal)
 	}
 	//skip newline after image
 	if !p.advanceMany(2) {
-		return nil, fmt.Errorf("parseImage: cannot advance")
+		return nil, p.errorf("parseImage: cannot advance")
 	}
 	if p.prevTok.Type != token.NEWLINE {
-		return nil, fmt.Errorf("parseImage: no NEWLINE after image")
+		return nil, p.errorf("parseImage: no NEWLINE after image")
 	}
 	return &ast.Image{Options: options, Path: matches[1]}, nil
 }
 
+var mediaRE = regexp.MustCompile(`^(video|audio)::(.*?)\[(.*)\]\s*$`)
+
+// parseMedia parses "video::file.mp4[width=640]", "video::id[youtube]" and "audio::file.mp3[]" macros
+func (p *Parser) parseMedia() (ast.Block, error) {
+	matches := mediaRE.FindStringSubmatch(p.tok.Literal)
+	if len(matches) != 4 {
+		return nil, p.errorf("invalid media literal: %v", p.tok.Literal)
+	}
+	if !p.advance() {
+		return nil, p.cannotAdvance()
+	}
+	if matches[1] == "audio" {
+		return ast.NewAudio(matches[2], matches[3]), nil
+	}
+	return ast.NewVideo(matches[2], matches[3]), nil
+}
+
 //include::RoutingGuide.adoc[leveloffset=+1]
 func (p *Parser) parseInclude(options string) (*ast.Document, error) {
 	var err error
 	matches := includeRE.FindStringSubmatch(p.tok.Literal)
 	if len(matches) != 3 {
-		return nil, fmt.Errorf("invalid include literal: %v", p.tok.Literal)
+		return nil, p.errorf("invalid include literal: %v", p.tok.Literal)
 	}
 	//skip newline after image
 	if !p.advanceMany(2) {
-		return nil, ErrCannotAdvance
+		return nil, p.cannotAdvance()
 	}
 	if p.prevTok.Type != token.NEWLINE {
-		return nil, fmt.Errorf("parseImage: no NEWLINE after image")
+		return nil, p.errorf("parseImage: no NEWLINE after image")
 	}
 	file := matches[1]
 	if strings.Contains(file, "yandex-counter.adoc") {
@@ -467,13 +1072,16 @@ func (p *Parser) parseInclude(options string) (*ast.Document, error) {
 	var data []byte
 	p.log.Debug(context.Background(), "parsing include file", slog.F("name", file), slog.F("leveloffset", levelOffset))
 	if p.f == nil {
-		return nil, fmt.Errorf("no callback, cannot get inlude file content: %v", file)
+		return nil, p.errorf("no callback, cannot get inlude file content: %v", file)
 	}
 	data, err = p.f(file)
 	if err != nil {
 		return nil, err
 	}
 	parser := New(string(data), p.f, p.log)
+	parser.attrs = p.attrs
+	parser.include = true
+	parser.diags = p.diags
 	var doc *ast.Document
 	doc, err = parser.Parse(file)
 	if err != nil {
@@ -504,11 +1112,11 @@ func (p *Parser) parseInclude(options string) (*ast.Document, error) {
 func (p *Parser) parseInlineImage() (*ast.InlineImage, error) {
 	matches := inlineImageRE.FindStringSubmatch(p.tok.Literal)
 	if len(matches) != 2 {
-		return nil, fmt.Errorf("invalid inline image literal: %v", p.tok.Literal)
+		return nil, p.errorf("invalid inline image literal: %v", p.tok.Literal)
 	}
 	//skip to the next token
 	if !p.advance() {
-		return nil, fmt.Errorf("parseInlineImage: cannot advance")
+		return nil, p.errorf("parseInlineImage: cannot advance")
 	}
 	return &ast.InlineImage{ Path: matches[1]}, nil
 }
@@ -527,11 +1135,11 @@ l1:
 		case p.isDoubleNewline() ||	p.isListMarker() ||	p.isColumn() || p.tok.Type == token.TABLE || p.tok.Type == token.EOF:
 			break l1
 		case p.tok.Type == token.NEWLINE:
-			if !p.advance() { return nil, fmt.Errorf("parse list item: cannot advance tokens") }
+			if !p.advance() { return nil, p.errorf("parse list item: cannot advance tokens") }
 		case p.tok.Type == token.CONCAT_PAR:
 			//skip newline after CONCAT_PAR
 			if !p.advanceMany(2) {
-				return nil, fmt.Errorf("parseListItem: cannot advance by 2 elements")
+				return nil, p.errorf("parseListItem: cannot advance by 2 elements")
 			}
 
 		default:
@@ -622,6 +1230,7 @@ func (p *Parser) parseList(parent *ast.List) (*ast.List, error) {
 	var blok ast.Block
 	var item *ast.ContainerBlock
 	var list ast.List
+	defer p.setRange(&list, p.tok)
 	if p.tok.Type == token.DEFL_MARK {
 		list.Definition = true
 		list.Marker = "::"
@@ -658,14 +1267,42 @@ func (p *Parser) parseList(parent *ast.List) (*ast.List, error) {
 				(p.tok.Type == token.CALLOUT_MARK && list.Callouts):
 			//current list item
 			var def string
+			marker := p.tok
 			if list.Definition {
 				def = p.tok.Literal
 			}
-			if !p.advance() {return nil, fmt.Errorf("parseList: cannot advance")}
+			if !p.advance() {return nil, p.errorf("parseList: cannot advance")}
+			var check *ast.CheckBox
+			if p.tok.Type == token.CHECKBOX {
+				//checklist item "* [x] text"
+				check = &ast.CheckBox{Checked: p.tok.Literal != " "}
+				p.setPos(check, p.tok, p.tok)
+				list.Checklist = true
+				if !p.advance() {return nil, p.errorf("parseList: cannot advance")}
+			}
 			item, err = p.parseListItem(def)
 			if err != nil {
 				return nil, err
 			}
+			if par, ok := firstParagraph(item); ok && def != "" {
+				//definition paragraph is the marker text
+				p.setPos(par, marker, marker)
+				p.setPos(par.Blocks[0], marker, marker)
+			}
+			p.setRange(item, marker)
+			if par, ok := firstParagraph(item); ok && len(par.Blocks) > 0 {
+				if _, ok := par.Blocks[0].(*ast.BibAnchor); ok {
+					list.Bibliography = true
+				}
+			}
+			if check != nil {
+				//checkbox becomes the first inline block of the item text
+				if par, ok := firstParagraph(item); ok {
+					par.Prepend(check)
+				} else {
+					item.Prepend(&ast.Paragraph{ContainerBlock: ast.ContainerBlock{Pos: check.Pos, Blocks: []ast.Block{check}}})
+				}
+			}
 			list.AddItem(item)
 		case p.isListMarker() && (list.CheckMarker(p.tok.Literal) || (p.tok.Type == token.DEFL_MARK && !list.Definition)):
 			//parent list item OR parent definition list item
@@ -681,13 +1318,21 @@ func (p *Parser) parseList(parent *ast.List) (*ast.List, error) {
 			list.LastItem().Add(blok)
 		default:
 			//error
-			return nil, fmt.Errorf("invalid nested list item")
+			return nil, p.errorf("invalid nested list item")
 		}
 	}
 
 	//return &list, nil
 }
 
+func firstParagraph(item *ast.ContainerBlock) (*ast.Paragraph, bool) {
+	if len(item.Blocks) == 0 {
+		return nil, false
+	}
+	par, ok := item.Blocks[0].(*ast.Paragraph)
+	return par, ok
+}
+
 func (p *Parser) parseTable(options string) (*ast.Table, error) {
 	//skip delimiter + newline tokens
 	var t ast.Table
@@ -699,24 +1344,29 @@ func (p *Parser) parseTable(options string) (*ast.Table, error) {
 	//defer func(old ast.Block) { p.curBlock = old }(p.curBlock)
 
 	if !p.advanceMany(2) {
-		return nil, fmt.Errorf("parse table: cannot advance tokens")
+		return nil, p.errorf("parse table: cannot advance tokens")
 	}
 	var countColumns = true
 	var cell *ast.ContainerBlock //current cell
+	var cellTok *token.Token //column token of the current cell
 
 	for p.tok.Type != token.TABLE && p.tok.Type != token.EOF {
 		switch {
 		case p.tok.Type == token.COLUMN || p.tok.Type == token.A_COLUMN: //new cell
+			span := ast.ParseCellSpan(p.tok.Literal)
 			if countColumns {
-				t.Columns++
+				t.Columns += span.Cols
 			}
 			if cell != nil {
+				p.setRange(cell, cellTok)
 				t.AddColumn(cell)
 			}
+			cellTok = p.tok
 			if !p.advance() {
-				return nil, ErrCannotAdvance
+				return nil, p.cannotAdvance()
 			}
 			cell = &ast.ContainerBlock{} //current cell
+			t.SetSpan(len(t.Cells), span)
 
 		case p.tok.Type == token.NEWLINE:
 			//stop counting at newline after some actual columns, thus "t.Columns>0"
@@ -724,29 +1374,37 @@ func (p *Parser) parseTable(options string) (*ast.Table, error) {
 				countColumns = false
 			}
 			if !p.advance() {
-				return nil, ErrCannotAdvance
+				return nil, p.cannotAdvance()
 			}
 		case p.tok.Type == token.INDENT:
 			if !p.advance() {
-				return nil, ErrCannotAdvance
+				return nil, p.cannotAdvance()
 			}
 		default:
+			from := p.tok
 			b, err := p.parseBlock()
 			if err != nil {
 				return nil, err
 			}
+			if _, ok := b.(*ast.Comment); ok {
+				//markdown tables can't contain comments
+				continue
+			}
 			if cell == nil {
-				return nil, fmt.Errorf("parse table: null cell")
+				return nil, p.errorAt(from, "parse table: null cell")
 			}
 			if b != nil {
 				cell.Add(b)
 			}
 		}
 	}
+	if cell != nil {
+		p.setRange(cell, cellTok)
+	}
 	if p.tok.Type == token.TABLE {
 		//skip closing token
 		if !p.advance() {
-			return nil, fmt.Errorf("parse table: cannot advance tokens")
+			return nil, p.errorf("parse table: cannot advance tokens")
 		}
 	}
 	t.AddColumn(cell)
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"asciidoc2md/ast"
+	"asciidoc2md/diag"
 	"bufio"
 	"cdr.dev/slog"
 	"cdr.dev/slog/sloggers/slogtest"
@@ -358,6 +360,7 @@ document:
 		input: `
 [[hdr1]]
 = Header 1
+
 Text after 1
 `,
 		expected: `
@@ -366,6 +369,353 @@ document:
   paragraph:
     text: Text after 1`,
 	},
+	{
+		name: "document header author line",
+		input: `
+[[hdr1]]
+= Header 1
+Text after 1
+`,
+		expected: `
+document:
+  header: 1, Header 1 [hdr1]`,
+	},
+	{
+		name: "checklist",
+		input: `* [x] done
+* [ ] todo`,
+		expected: `
+document:
+  list begin: (0/false/*) checklist
+  item:
+    container block:
+      paragraph:
+        checkbox: [x]
+        text: done
+  item:
+    container block:
+      paragraph:
+        checkbox: [ ]
+        text: todo
+  list end`,
+	},
+	{
+		name: "ordered list style",
+		input: `[upperroman%reversed, start=4]
+. four
+. three`,
+		expected: `
+document:
+  list begin: (0/true/.) upperroman start=4 reversed
+  item 1:
+    container block:
+      paragraph:
+        text: four
+  item 2:
+    container block:
+      paragraph:
+        text: three
+  list end`,
+	},
+	{
+		name: "line breaks",
+		input: `first +
+second
+third
+
+[%hardbreaks]
+one
+two`,
+		expected: `
+document:
+  paragraph:
+    text: first
+    line break
+    text: second
+    text: 
+
+    text: third
+  paragraph:
+    text: one
+    line break
+    text: two`,
+	},
+	{
+		name: "index terms",
+		input: `[[id1,Header text]]
+== Header
+
+A ((visible)) term (((first, second))) and [[id2,anchor text]] anchor.`,
+		expected: `
+document:
+  header: 2, Header [id1], reftext: Header text
+  paragraph:
+    text: A 
+    index term: (visible) visible
+    text:  term 
+    index term: (concealed) first, second
+    text:  and 
+    bookmark: id2, anchor text
+    text:  anchor.`,
+	},
+	{
+		name: "empty index terms",
+		input: `Empty (( )) and ((( ))) terms`,
+		expected: `
+document:
+  paragraph:
+    text: Empty 
+    text: (( ))
+    text:  and 
+    text: ((( )))
+    text:  terms`,
+	},
+	{
+		name: "ui macros",
+		input: `:experimental:
+
+kbd:[Ctrl++] btn:[Save] menu:File[Save As > Copy] kbd:[Alt,F4]`,
+		expected: `
+document:
+  paragraph:
+    kbd: Ctrl, +
+    text:  
+    button: Save
+    text:  
+    menu: File > Save As > Copy
+    text:  
+    kbd: Alt, F4`,
+	},
+	{
+		name: "ui macros disabled",
+		input: `kbd:[Ctrl+S]`,
+		expected: `
+document:
+  paragraph:
+    text: kbd:[Ctrl+S]`,
+	},
+	{
+		name: "comments",
+		input: `// line 1
+// line 2
+text
+
+////
+* not a list
+////`,
+		expected: `
+document:
+  comment: line 1
+line 2
+  paragraph:
+    text: text
+  comment block: * not a list`,
+	},
+	{
+		name: "hardbreaks attribute",
+		input: `:hardbreaks-option:
+
+one
+two`,
+		expected: `
+document:
+  paragraph:
+    text: one
+    line break
+    text: two`,
+	},
+	{
+		name: "toc macro",
+		input: `:toc-title: Contents
+
+toc::[]
+
+text`,
+		expected: `
+document:
+  toc: Contents
+  paragraph:
+    text: text`,
+	},
+	{
+		name: "bibliography",
+		input: `See <<pp>>.
+
+[bibliography]
+== References
+
+* [[[pp]]] Andy Hunt
+* [[[gof,GoF]]] Erich Gamma`,
+		expected: `
+document:
+  paragraph:
+    text: See 
+    link: (true,,pp)
+    text: .
+  header: 2, References, bibliography
+  list begin: (0/false/*)
+  item:
+    container block:
+      paragraph:
+        bib anchor: pp, pp
+        text:  Andy Hunt
+  item:
+    container block:
+      paragraph:
+        bib anchor: gof, GoF
+        text:  Erich Gamma
+  list end`,
+	},
+	{
+		name: "stem",
+		input: `:stem: latexmath
+
+[stem]
+++++
+\sqrt{4} = 2
+++++
+
+[asciimath]
+++++
+sqrt(4) = 2
+++++
+
+Inline stem:[x^2] and asciimath:[[a, b\]].
+
+++++
+<p>raw html</p>
+++++`,
+		expected: `
+document:
+  stem: latexmath, \sqrt{4} = 2
+  stem: asciimath, sqrt(4) = 2
+  paragraph:
+    text: Inline 
+    inline stem: latexmath, x^2
+    text:  and 
+    inline stem: asciimath, [a, b]
+    text: .
+  pass block: "<p>raw html</p>\n"`,
+	},
+	{
+		name: "diagrams",
+		input: `[plantuml, auth-flow, svg]
+----
+Alice -> Bob
+----
+
+[mermaid, target=states]
+....
+graph TD
+....
+
+[source, sql]
+----
+select 1
+----`,
+		expected: `
+document:
+  diagram: plantuml, auth-flow, svg, "Alice -> Bob\n"
+  diagram: mermaid, states, , "graph TD\n"
+  syntax block: "select 1\n"`,
+	},
+	{
+		name: "media",
+		input: `video::rPQoq7ThGAU[youtube]
+
+video::intro.mp4[width=640]
+audio::sound.mp3[]`,
+		expected: `
+document:
+  video: rPQoq7ThGAU, youtube
+  video: intro.mp4, 
+  audio: sound.mp3`,
+	},
+	{
+		name: "link attributes",
+		input: `https://a.org[Docs^] https://b.org["Text, comma", window=_self, role="ext big"] e@f.org`,
+		expected: `
+document:
+  paragraph:
+    link: (false,Docs,https://a.org), window: _blank
+    text:  
+    link: (false,Text, comma,https://b.org), window: _self, roles: ext big
+    text:  
+    link: (false,e@f.org,mailto:e@f.org)`,
+	},
+	{
+		name: "section numbers",
+		input: `:sectnums:
+:sectnumlevels: 2
+
+== One
+
+=== Two
+
+==== Not numbered
+
+[glossary]
+== Glossary
+
+=== Not numbered
+
+== Three
+
+[appendix]
+== Extra
+
+=== Details`,
+		expected: `
+document:
+  header: 2, One, number: 1.
+  header: 3, Two, number: 1.1.
+  header: 4, Not numbered
+  header: 2, Glossary, glossary
+  header: 3, Not numbered
+  header: 2, Three, number: 2.
+  header: 2, Extra, appendix, number: Appendix A.
+  header: 3, Details, number: A.1.`,
+	},
+	{
+		name: "table spans",
+		input: `|===
+2+|wide |right
+.2+|tall |a |b
+|c |d
+|===`,
+		expected: `
+document:
+  table begin: 3 cols (simple) (not-so-simple)
+  cell (span 2x1):
+    container block:
+      paragraph:
+        text: wide 
+  cell:
+    container block:
+      paragraph:
+        text: right
+  cell (span 1x2):
+    container block:
+      paragraph:
+        text: tall 
+  cell:
+    container block:
+      paragraph:
+        text: a 
+  cell:
+    container block:
+      paragraph:
+        text: b
+  cell:
+    container block:
+      paragraph:
+        text: c 
+  cell:
+    container block:
+      paragraph:
+        text: d
+  table end`,
+	},
 }
 
 func testACase(t *testing.T, tc *parserTestCase, log slog.Logger) {
@@ -436,6 +786,46 @@ expected:
 ``,
 }
 
+func TestDocumentHeader(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	input := `= Guide: The Subtitle
+Kismet R. Lee <kismet@asciidoctor.org>; Doc_Writer
+v1.0, 2019-01-01: First draft
+:description: Guide description
+// comment
+:toc:
+
+text`
+	doc, err := New(input, nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	exp := &ast.DocumentHeader{
+		Title:    "Guide",
+		Subtitle: "The Subtitle",
+		Authors: []ast.Author{{Name: "Kismet R. Lee", Email: "kismet@asciidoctor.org"}, {Name: "Doc Writer"}},
+		Revision: ast.Revision{Number: "1.0", Date: "2019-01-01", Remark: "First draft"},
+		Attributes: map[string]string{"description": "Guide description", "toc": ""},
+	}
+	assert.Equal(t, exp, doc.Header)
+	assert.Equal(t, "\ndocument:\n  header: 1, Guide: The Subtitle\n  paragraph:\n    text: text", doc.StringWithIndent(""))
+
+	doc, err = New("= Title\nJohn Doe\n2020-05-01\n\ntext", nil, logger).Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ast.Revision{Date: "2020-05-01"}, doc.Header.Revision)
+
+	//revision line is read right after the author line only, text lines aren't taken for it
+	for _, input := range []string{"= Title\n2 steps to install", "= Title\nJohn Doe\n2 steps to install"} {
+		doc, err = New(input, nil, logger).Parse("test.adoc")
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, ast.Revision{}, doc.Header.Revision, input)
+			assert.Contains(t, doc.StringWithIndent(""), "text: 2 steps to install", input)
+		}
+	}
+}
+
 func TestParserDbg1(t *testing.T) {
 	logger := slogtest.Make(t, nil)
 	logger.Info(context.Background(), "log message")
@@ -444,3 +834,84 @@ func TestParserDbg1(t *testing.T) {
 	testACase(t, &case1, logger)
 }
 
+
+func TestPositions(t *testing.T) {
+	logger := slogtest.Make(t, nil)
+	input := `== Header
+
+Some text link:https://example.org[link] end.
+
+[source,go]
+----
+fmt.Println()
+----
+
+* item 1
+** nested
+* item 2
+
+include::inc.adoc[]
+`
+	p := New(input, func(name string) ([]byte, error) {
+		return []byte("included paragraph\n"), nil
+	}, logger)
+	doc, err := p.Parse("dir/test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	pos := func(b ast.Block) ast.Pos {
+		return *b.(ast.Positioned).Position()
+	}
+	cases := []struct {
+		block ast.Block
+		pos   ast.Pos
+	}{
+		{doc, ast.Pos{File: "dir/test.adoc", Line: 1, Col: 1, EndLine: 15, EndCol: 1}},
+		{doc.Blocks[0], ast.Pos{File: "dir/test.adoc", Line: 1, Col: 1, EndLine: 1, EndCol: 9}},
+		{doc.Blocks[1], ast.Pos{File: "dir/test.adoc", Line: 3, Col: 1, EndLine: 3, EndCol: 45}},
+		{doc.Blocks[1].(*ast.Paragraph).Blocks[1], ast.Pos{File: "dir/test.adoc", Line: 3, Col: 11, EndLine: 3, EndCol: 40}},
+		{doc.Blocks[2], ast.Pos{File: "dir/test.adoc", Line: 5, Col: 1, EndLine: 8, EndCol: 4}},
+		{doc.Blocks[3], ast.Pos{File: "dir/test.adoc", Line: 10, Col: 1, EndLine: 12, EndCol: 8}},
+		{doc.Blocks[3].(*ast.List).Items[0].Blocks[1], ast.Pos{File: "dir/test.adoc", Line: 11, Col: 1, EndLine: 11, EndCol: 9}},
+		{doc.Blocks[4].(*ast.Document).Blocks[0], ast.Pos{File: "inc.adoc", Line: 1, Col: 1, EndLine: 1, EndCol: 18}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.pos, pos(c.block), c.block.String())
+	}
+
+	_, err = New("text\n\n|===\nno cells\n|===\n", nil, logger).Parse("table.adoc")
+	if assert.Error(t, err) {
+		assert.Equal(t, "table.adoc:4:1: parse table: null cell", err.Error())
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	logger := slogtest.Make(t, nil)
+	input := "text 1\n\n|===\nno cells\n|===\n\n====\ninner\n\n|===\nbad\n|===\n====\n\ntext 2\n"
+	var diags diag.Collector
+	p := New(input, nil, logger)
+	p.SetDiagnostics(&diags)
+	doc, err := p.Parse("test.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `
+document:
+  paragraph:
+    text: text 1
+  unknown: "|===\nno cells\n|==="
+  example block:
+    paragraph:
+      text: inner
+    unknown: "|===\nbad\n|==="
+  paragraph:
+    text: text 2`, doc.StringWithIndent(""))
+	var msgs []string
+	for _, d := range diags.Diagnostics() {
+		msgs = append(msgs, d.Error())
+	}
+	assert.Equal(t, []string{"test.adoc:4:1: parse table: null cell", "test.adoc:11:1: parse table: null cell"}, msgs)
+	u := doc.Blocks[1].(*ast.Unknown)
+	assert.Equal(t, "|===\nno cells\n|===", u.Text)
+	assert.Equal(t, ast.Pos{File: "test.adoc", Line: 3, Col: 1, EndLine: 5, EndCol: 4}, u.Pos)
+}
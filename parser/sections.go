@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"asciidoc2md/ast"
+	"strconv"
+	"strings"
+)
+
+const defaultSectNumLevels = 3
+
+// special sections are never numbered, see https://docs.asciidoctor.org/asciidoc/latest/sections/special-section-numbers/
+var specialSections = map[string]bool{
+	"abstract":        true,
+	"acknowledgments": true,
+	"bibliography":    true,
+	"colophon":        true,
+	"dedication":      true,
+	"glossary":        true,
+	"index":           true,
+	"preface":         true,
+}
+
+// numberSections computes section numbers of the document headers if ":sectnums:" attribute is set.
+// Appendices are lettered even if the sections aren't numbered: "Appendix A.", "A.1.", ...
+func numberSections(doc *ast.Document) {
+	_, sectNums := doc.Attributes["sectnums"]
+	levels := defaultSectNumLevels
+	if v, err := strconv.Atoi(doc.Attributes["sectnumlevels"]); err == nil {
+		levels = v
+	}
+	caption, ok := doc.Attributes["appendix-caption"]
+	if !ok {
+		caption = "Appendix"
+	}
+	var counters []int
+	var special bool
+	var appendix string //letter of the current appendix
+	var appendices int
+	doc.Walk(func(b ast.Block, root *ast.Document) bool {
+		h, ok := b.(*ast.Header)
+		if !ok || h.Float || h.Level < 2 {
+			//document title isn't numbered
+			return true
+		}
+		//"==" header is the section level 1
+		level := h.Level - 1
+		if level == 1 {
+			special = specialSections[h.Style]
+			appendix = ""
+			if h.Style == "appendix" {
+				appendix = string(rune('A' + appendices%26))
+				appendices++
+				if len(counters) > 1 {
+					//appendix subsections are numbered from 1
+					counters = counters[:1]
+				}
+				h.Number = strings.TrimSpace(caption + " " + appendix + ".")
+				return true
+			}
+		}
+		if special || specialSections[h.Style] {
+			return true
+		}
+		for len(counters) < level {
+			counters = append(counters, 0)
+		}
+		counters = counters[:level]
+		counters[level-1]++
+		if !sectNums || level > levels {
+			return true
+		}
+		parts := make([]string, 0, level)
+		for i, c := range counters {
+			if i == 0 && appendix != "" {
+				parts = append(parts, appendix)
+			} else {
+				parts = append(parts, strconv.Itoa(c))
+			}
+		}
+		h.Number = strings.Join(parts, ".") + "."
+		return true
+	}, nil)
+}
@@ -1,6 +1,9 @@
 package settings
 
-import "gopkg.in/yaml.v3"
+import (
+	"gopkg.in/yaml.v3"
+	"path/filepath"
+)
 
 type Headers2FileMap map[string]string
 
@@ -13,11 +16,171 @@ type Config struct {
 	IdMapFallbacks map[string]string `yaml:"idmap_fallbacks"`
 	// if link contains a specified key, then it's replaced with the provided value
 	UrlRewrites []Headers2FileMap `yaml:"url_rewrites"`
+	// document name -> index page file name, e.g. UserGuide.adoc -> index_terms.md
+	IndexPages map[string]string `yaml:"index_pages,omitempty"`
+	// document name -> header text -> front matter template of the file starting with the header,
+	// "*" header template is applied to every file of the document
+	FrontMatter map[string]map[string]*FrontMatter `yaml:"front_matter,omitempty"`
+	// markdown output options
+	Markdown MarkdownOptions `yaml:"markdown"`
+	// number of the parse errors tolerated, the errors are reported and the broken source is written as is
+	MaxErrors int `yaml:"max_errors,omitempty"`
+	// documentation set converted by "build" command
+	Project *Project `yaml:"project,omitempty"`
 	NavFile string `yaml:"-"`
 	InputFile string `yaml:"-"`
 	ArtifactsDir string `yaml:"-"`
 }
 
+// Hard line break styles
+const (
+	HardBreakSpaces    = "spaces"    // two trailing spaces, default
+	HardBreakBackslash = "backslash" // trailing backslash, not supported by Python-Markdown
+)
+
+// UI macros output styles
+const (
+	UIMacrosMkDocs = "mkdocs" // "++ctrl+s++" keys (pymdownx.keys extension), bold buttons and menus, default
+	UIMacrosHtml   = "html"   // <kbd>, <span class="btn"> and <span class="menuseq"> html tags
+)
+
+// Markdown flavors
+const (
+	FlavorMkDocs     = "mkdocs"     // MkDocs Material, default
+	FlavorGFM        = "gfm"        // GitHub Flavored Markdown
+	FlavorCommonMark = "commonmark" // plain CommonMark
+	FlavorHugo       = "hugo"       // Hugo with Relearn theme shortcodes
+	FlavorDocusaurus = "docusaurus" // Docusaurus MDX
+)
+
+type MarkdownOptions struct {
+	// markdown dialect: "mkdocs", "gfm", "commonmark", "hugo" or "docusaurus"
+	Flavor string `yaml:"flavor,omitempty"`
+	// hard line break style: "spaces" or "backslash"
+	HardBreak string `yaml:"hard_break,omitempty"`
+	// kbd, btn and menu macros style: "mkdocs" or "html", "html" is the default for the flavors other than "mkdocs"
+	UIMacros string `yaml:"ui_macros,omitempty"`
+	// write asciidoc comments as html comments "<!-- -->"
+	KeepComments bool `yaml:"keep_comments,omitempty"`
+	// write YAML front matter (title, authors, date, description, ...) at the beginning of every markdown file,
+	// front matter is also written if there are front matter templates for the document
+	FrontMatter bool `yaml:"front_matter,omitempty"`
+	// write AsciiMath formulas as is instead of converting them to LaTeX
+	KeepAsciiMath bool `yaml:"keep_asciimath,omitempty"`
+	// reference images pre-rendered by Asciidoctor Diagram instead of writing diagram sources,
+	// only diagrams with the target name are referenced
+	DiagramImages bool `yaml:"diagram_images,omitempty"`
+}
+
+// FrontMatter is a YAML metadata block written at the beginning of every split markdown file
+type FrontMatter struct {
+	Title       string   `yaml:"title,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Authors     []string `yaml:"authors,omitempty"`
+	Date        string   `yaml:"date,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	// mkdocs material page elements to hide: toc, navigation, footer
+	Hide   []string      `yaml:"hide,omitempty"`
+	Search *SearchOptions `yaml:"search,omitempty"`
+	// source asciidoc file and line: "UserGuide.adoc:120"
+	Source string `yaml:"source,omitempty"`
+	// any other keys are written as is
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// SearchOptions is mkdocs material search tuning
+type SearchOptions struct {
+	Boost   float64 `yaml:"boost,omitempty"`
+	Exclude bool    `yaml:"exclude,omitempty"`
+}
+
+// Project lists the guides of the documentation set, relative paths are resolved against the current folder
+type Project struct {
+	// root folder of the guide sources
+	SrcDir string `yaml:"src_dir,omitempty"`
+	// root folder of the output, e.g. mkdocs "docs" folder
+	DestDir string `yaml:"dest_dir,omitempty"`
+	// folder of the .idmap files, "idmaps" by default
+	ArtifactsDir string `yaml:"artifacts_dir,omitempty"`
+	Guides []*Guide `yaml:"guides"`
+}
+
+// Guide is the document converted into its own output folder
+type Guide struct {
+	// short name used as the slug of the output files: "user" gives user_1.md, user_2.md, ...
+	Name string `yaml:"name"`
+	// source .adoc file relative to the project src_dir
+	Src string `yaml:"src"`
+	// output folder relative to the project dest_dir
+	Dest string `yaml:"dest"`
+	// level of the headers to split the document at, 2 by default
+	SplitLevel int `yaml:"split_level,omitempty"`
+	// navigation file (mkdocs.yml or .pages) relative to the project dest_dir, navigation isn't written if empty
+	NavFile string `yaml:"nav_file,omitempty"`
+	// relative path to the images folder used in the output files, "images/" by default
+	ImagesDir string `yaml:"images_dir,omitempty"`
+}
+
+const (
+	DefaultArtifactsDir = "idmaps"
+	DefaultSplitLevel   = 2
+	DefaultImagesDir    = "images/"
+)
+
+// Guide returns the guide by its name, nil if there is no such guide
+func (p *Project) Guide(name string) *Guide {
+	for _, g := range p.Guides {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func (p *Project) Artifacts() string {
+	if p.ArtifactsDir == "" {
+		return DefaultArtifactsDir
+	}
+	return p.ArtifactsDir
+}
+
+func (p *Project) SrcPath(g *Guide) string {
+	return joinPath(p.SrcDir, g.Src)
+}
+
+func (p *Project) DestPath(g *Guide) string {
+	return joinPath(p.DestDir, g.Dest)
+}
+
+// NavPath returns the navigation file path, empty if the guide has no navigation file
+func (p *Project) NavPath(g *Guide) string {
+	if g.NavFile == "" {
+		return ""
+	}
+	return joinPath(p.DestDir, g.NavFile)
+}
+
+func (g *Guide) Level() int {
+	if g.SplitLevel == 0 {
+		return DefaultSplitLevel
+	}
+	return g.SplitLevel
+}
+
+func (g *Guide) Images() string {
+	if g.ImagesDir == "" {
+		return DefaultImagesDir
+	}
+	return g.ImagesDir
+}
+
+func joinPath(root string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
 func Parse(data []byte) (*Config, error) {
 	conf := Config{}
 	err := yaml.Unmarshal(data, &conf)
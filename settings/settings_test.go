@@ -3,6 +3,7 @@ package settings
 import (
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
+	"io/ioutil"
 	"testing"
 )
 
@@ -33,9 +34,50 @@ idmap_fallbacks:
   map1.adoc.idmap: map2.adoc.idmap
 cross_links:
   file.adoc: relative/path
+markdown:
+  hard_break: backslash
+front_matter:
+  file.adoc:
+    "*":
+      tags: [tag1]
+    header 1:
+      hide: [toc]
+      search:
+        boost: 2
+      template: page.html
+project:
+  src_dir: /src
+  dest_dir: docs
+  guides:
+    - name: user
+      src: UserGuide/UserGuide.adoc
+      dest: usr/user
+      nav_file: usr/user/.pages
+    - name: kb
+      src: /other/BestPractices.adoc
+      dest: dev/dev/kb
+      split_level: 3
+      images_dir: ../images/
 `
 	conf, err := Parse([]byte(input))
 	assert.NoError(t, err)
+	assert.Equal(t, HardBreakBackslash, conf.Markdown.HardBreak)
+	assert.Equal(t, 2.0, conf.FrontMatter["file.adoc"]["header 1"].Search.Boost)
+	assert.Equal(t, "page.html", conf.FrontMatter["file.adoc"]["header 1"].Extra["template"])
+	if p := conf.Project; assert.NotNil(t, p) && assert.Len(t, p.Guides, 2) {
+		user, kb := p.Guide("user"), p.Guide("kb")
+		assert.Nil(t, p.Guide("none"))
+		assert.Equal(t, DefaultArtifactsDir, p.Artifacts())
+		assert.Equal(t, "/src/UserGuide/UserGuide.adoc", p.SrcPath(user))
+		assert.Equal(t, "docs/usr/user", p.DestPath(user))
+		assert.Equal(t, "docs/usr/user/.pages", p.NavPath(user))
+		assert.Equal(t, DefaultSplitLevel, user.Level())
+		assert.Equal(t, DefaultImagesDir, user.Images())
+		assert.Equal(t, "/other/BestPractices.adoc", p.SrcPath(kb))
+		assert.Equal(t, "", p.NavPath(kb))
+		assert.Equal(t, 3, kb.Level())
+		assert.Equal(t, "../images/", kb.Images())
+	}
 	//t.Logf("%+v", conf)
 	data, err := yaml.Marshal(conf)
 	assert.NoError(t, err)
@@ -45,4 +87,18 @@ cross_links:
 	//conf2.CrossLinks["file.adoc"] = "sdf"
 	//t.Logf("%+v", conf2)
 	assert.Equal(t, conf, conf2)
-}
\ No newline at end of file
+}
+func TestShippedSettings(t *testing.T) {
+	data, err := ioutil.ReadFile("../settings.yml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	conf, err := Parse(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "BestPractices.adoc", conf.IdMapFallbacks["ProgrammersGuide.adoc"])
+	if assert.NotNil(t, conf.Project) {
+		assert.NotEmpty(t, conf.Project.Guides)
+	}
+}
@@ -2,45 +2,58 @@ package main
 
 import (
 	"asciidoc2md/ast"
+	"asciidoc2md/idmap"
 	"asciidoc2md/markdown"
 	"asciidoc2md/settings"
 	"asciidoc2md/utils"
 	"bufio"
+	"bytes"
 	"cdr.dev/slog"
 	"context"
 	"errors"
 	"fmt"
-	"gopkg.in/yaml.v3"
 	"io"
 	"io/ioutil"
-	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 )
 
-type IdMapEntry struct {
-	FileName string
-	Caption string
-}
-type IdMap map[string]*IdMapEntry
+type IdMapEntry = idmap.Entry
+type IdMap = idmap.Map
 
 type FileSplitter struct {
 	doc         *ast.Document
 	conf 		*settings.Config
 	//headerMap   map[string]string //header -> file name
 	idMaps      map[string]IdMap  //document name -> header or bookmark id -> output file name
+	registry    *idmap.Registry //idmaps of the other documents, shared by the splitters
+	sources     []string //document and included files, their hash is stored in the idmap
 	log         slog.Logger
 	slug        string
 	path        string //output path
 	level       int //split at the specified level headers
 	firstHeader *ast.Header
+	firstHeaderDoc string //name of the document containing the first header
 	fileIndex   int
 	fileName    string //current fileName
 	fileNames   []string //all the filenames
-	file        *os.File 	//current file
+	fileHeaders []fileHeader //first headers of the files, the same order as fileNames
+	buf         *bytes.Buffer //content of the current file, it is written on Close
 	w           *bufio.Writer  	//current writer
+	updated     int //number of the output files written, unchanged files are not rewritten
+	index       []indexEntry //index terms occurrences
+	toc         []tocItem //all the headers in the document order
+	ext         string //output file extension, ".md" if empty
+	single      bool //write the whole document into a single file
+}
+
+// fileHeader is the header the output file starts with
+type fileHeader struct {
+	Header *ast.Header
+	Doc    string //name of the (included) document containing the header
 }
 
 const (
@@ -54,6 +67,7 @@ func NewFileSplitter(doc *ast.Document, nameSlug string, conf *settings.Config,
 		doc:    doc,
 		conf: 	conf,
 		idMaps:	make(map[string]IdMap),
+		registry: idmap.New(idmap.NewDir(conf.ArtifactsDir)),
 		level:  splitLvl,
 		log:    log,
 		slug:   nameSlug,
@@ -79,8 +93,8 @@ func (fs *FileSplitter) RenderMarkdown(imagePath string) error {
 	if err != nil {
 		return err
 	}
-	defer fs.Close()
 
+	var writeErr error //the first error of writing the output files
 	conv := markdown.New(imagePath, nil, fs.log, func(header *ast.Header) io.Writer {
 		if header.Level < fs.level && fs.level != 1 {
 			header.Text = SkipHeaderMark
@@ -91,7 +105,10 @@ func (fs *FileSplitter) RenderMarkdown(imagePath string) error {
 			}
 			err := fs.nextFile()
 			if err != nil {
-				fs.log.Error(context.Background(), err.Error())
+				fs.log.Error(context.Background(), err.Error(), slog.F("pos", header.Location()))
+				if writeErr == nil {
+					writeErr = err
+				}
 				return nil
 			}
 			fs.decreaseHeader(header)
@@ -101,62 +118,119 @@ func (fs *FileSplitter) RenderMarkdown(imagePath string) error {
 		fs.decreaseHeader(header)
 		return nil
 	})
+	conv.SetOptions(fs.conf.Markdown)
 	conv.RenderMarkdown(fs.doc, fs.w)
+	if err = fs.closeFile(); writeErr == nil {
+		writeErr = err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if name := fs.conf.IndexPages[fs.doc.Name]; name != "" {
+		return fs.writeIndexPage(filepath.Join(fs.path, name))
+	}
 	return nil
 }
 
 func (fs *FileSplitter) getNextFileName(h *ast.Header) string {
 	fs.fileIndex++
+	ext := fs.ext
+	if ext == "" {
+		ext = ".md"
+	}
+	if fs.single {
+		return fs.slug + ext
+	}
 	if h == nil {
-		return fmt.Sprintf("%s_%v.md", fs.slug, fs.fileIndex)
+		return fmt.Sprintf("%s_%v%s", fs.slug, fs.fileIndex, ext)
 	}
 	name, ok := fs.conf.Headers[fs.doc.Name][h.Text]
 	if !ok {
 		//fs.log.Info(context.Background(), "no file name for h", slog.F("h", h.Text))
-		return fmt.Sprintf("%s_%v.md", fs.slug, fs.fileIndex)
+		return fmt.Sprintf("%s_%v%s", fs.slug, fs.fileIndex, ext)
+	}
+	if name != SkipChapterMark && ext != ".md" {
+		//configured names are markdown file names
+		name = strings.TrimSuffix(name, ".md") + ext
 	}
 	return name
 }
 
 func (fs *FileSplitter) nextFile() error {
 	//close previous file
-	fs.Close()
-	var err error
+	err := fs.closeFile()
+	if err != nil {
+		return err
+	}
 	fs.fileName = fs.fileNames[fs.fileIndex]
 	if fs.fileName == SkipChapterMark {
 		fs.w = bufio.NewWriter(ioutil.Discard)
-		fs.file = nil
 		fs.log.Warn(context.Background(), "discard writer created")
 		return nil
 	}
 
-	fullName := filepath.Join(fs.path, fs.fileName)
-	fs.file, err = os.Create(fullName)
-	if err != nil {
+	fs.buf = &bytes.Buffer{}
+	fs.w = bufio.NewWriter(fs.buf)
+	if err = fs.writeFrontMatter(); err != nil {
 		return err
 	}
-
-	fs.log.Debug(context.Background(), "output file created", slog.F("file", fullName))
-	fs.w = bufio.NewWriter(fs.file)
 	fs.fileIndex++
 
 	return nil
 }
 
-func (fs *FileSplitter) Close() {
-	//close previous files
+// writeFrontMatter writes front matter of the current file if it is enabled or there are templates for the document
+func (fs *FileSplitter) writeFrontMatter() error {
+	templates := fs.conf.FrontMatter[fs.doc.Name]
+	if !fs.conf.Markdown.FrontMatter && templates == nil {
+		return nil
+	}
+	fh := fs.fileHeaders[fs.fileIndex]
+	var tmpl *settings.FrontMatter
+	if fh.Header != nil {
+		tmpl = templates[fh.Header.Text]
+	}
+	fm := markdown.NewFrontMatter(fs.doc, fh.Header, fh.Doc, templates["*"], tmpl)
+	return markdown.WriteFrontMatter(fm, fs.w)
+}
+
+// Close writes the current file if it is not written yet
+func (fs *FileSplitter) Close() error {
+	return fs.closeFile()
+}
+
+// closeFile writes the content of the current file
+func (fs *FileSplitter) closeFile() error {
 	if fs.w != nil {
 		fs.w.Flush()
 	}
-	if fs.file != nil {
-		fs.file.Close()
+	if fs.buf == nil {
+		return nil
+	}
+	buf := fs.buf
+	fs.buf = nil
+	return fs.writeFile(filepath.Join(fs.path, fs.fileName), buf.Bytes())
+}
+
+// writeFile writes the output file if its content is changed, so the watchers of the output folder
+// (e.g. "mkdocs serve") don't rebuild unchanged pages
+func (fs *FileSplitter) writeFile(name string, data []byte) error {
+	if old, err := ioutil.ReadFile(name); err == nil && bytes.Equal(old, data) {
+		fs.log.Debug(context.Background(), "output file unchanged", slog.F("file", name))
+		return nil
+	}
+	if err := ioutil.WriteFile(name, data, 0666); err != nil {
+		return err
 	}
+	fs.updated++
+	fs.log.Debug(context.Background(), "output file written", slog.F("file", name))
+	return nil
 }
 
 func (fs *FileSplitter) skipChapter(h *ast.Header) bool {
 	m, ko := fs.conf.Headers[fs.doc.Name][h.Text]
 	if ko && m == SkipChapterMark {
-		fs.log.Warn(context.Background(), "skipping chapter", slog.F("chapter", h.Text))
+		fs.log.Warn(context.Background(), "skipping chapter", slog.F("pos", h.Location()), slog.F("chapter", h.Text))
 		return true
 	}
 	return false
@@ -168,6 +242,7 @@ func (fs *FileSplitter) findFirstHeader() *ast.Header {
 		h, ok := b.(*ast.Header)
 		if ok && h.Level == fs.level && !h.Float && !fs.skipChapter(h) {
 			hdr = h
+			fs.firstHeaderDoc = doc.Name
 			return false
 		}
 		return true
@@ -245,7 +320,7 @@ func (fs *FileSplitter) linkRewrite(link *ast.Link, root *ast.Document) {
 
 	if !link.Internal && rule == "" {
 		// external link without rewrite rule
-		fs.log.Debug(ctx, "external link without rewrite rule", slog.F("link", link))
+		fs.log.Debug(ctx, "external link without rewrite rule", slog.F("pos", link.Location()), slog.F("link", link))
 		return
 	}
 
@@ -268,7 +343,7 @@ func (fs *FileSplitter) linkRewrite(link *ast.Link, root *ast.Document) {
 		}
 		fs.log.Debug(ctx, "successfully rewrote link", slog.F("new", link.Url), slog.F("old", old))
 	} else {
-		fs.log.Error(ctx, "cannot rewrite link: idmap is not found", slog.F("link", link), slog.F("doc", root.Name))
+		fs.log.Error(ctx, "cannot rewrite link: idmap is not found", slog.F("pos", link.Location()), slog.F("link", link), slog.F("doc", root.Name))
 		//link.Url = fmt.Sprintf("%v#%v", adocRef, idRef)
 	}
 }
@@ -292,16 +367,12 @@ func (fs *FileSplitter) fixUrls() {
 
 }
 
-func (fs *FileSplitter) writeIdMap(name string, idMap IdMap) error {
-/*	f, err := os.Create(name)
+func (fs *FileSplitter) writeIdMap(m IdMap) error {
+	f, err := idmap.NewFile(fs.doc.Name, fs.slug, fs.level, fs.sources, m)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-*/	out, err := yaml.Marshal(idMap)
-//	_, err = f.Write(out)
-	err = ioutil.WriteFile(name, out, 0666)
-	return err
+	return fs.registry.Put(f)
 }
 
 func (fs *FileSplitter) getDocPath(doc string) string {
@@ -328,8 +399,11 @@ func (fs *FileSplitter)	fillIdMap(printYaml bool) {
 		fs.fileName = ""
 		return
 	}
-	nav := []string{fmt.Sprintf("- %s: %s", fs.firstHeader.Text, fs.fileName)}
+	nav := []string{fmt.Sprintf("- %s: %s", fs.firstHeader.Title(), fs.fileName)}
 	skipCurChapter := false
+	var curHeader *ast.Header
+	fs.index = nil
+	fs.toc = nil
 	fs.doc.Walk(func(b ast.Block, root *ast.Document) bool {
 
 		//fs.log.Debug(ctx, "walker block", slog.F("block", b))
@@ -348,17 +422,36 @@ func (fs *FileSplitter)	fillIdMap(printYaml bool) {
 				} else {
 					skipCurChapter = false
 					fs.fileName = fs.getNextFileName(hd)
-					nav = append(nav, fmt.Sprintf("- %s: %s", hd.Text, fs.fileName))
+					nav = append(nav, fmt.Sprintf("- %s: %s", hd.Title(), fs.fileName))
 				}
 				fs.fileNames = append(fs.fileNames, fs.fileName)
+				fs.fileHeaders = append(fs.fileHeaders, fileHeader{hd, root.Name})
 			}
 			if !skipCurChapter {
-				fs.appendIdMap(fs.doc.Name, hd.Id, fs.fileName, hd.Text)
+				fs.appendIdMap(fs.doc.Name, hd.Id, fs.fileName, hd.Title())
+				if hd.RefText != "" {
+					fs.appendAnchor(hd.Id, fs.fileName, hd.RefText)
+				}
+				fs.appendTocItem(hd)
+			}
+			if !hd.Float {
+				curHeader = hd
 			}
 		case *ast.Bookmark:
 			//fs.log.Debug(ctx, "walking by bookmark", slog.F("header", b.(*ast.Bookmark)))
 			if  !skipCurChapter {
-				fs.appendIdMap(fs.doc.Name, b.(*ast.Bookmark).Literal, fs.fileName, "")
+				bm := b.(*ast.Bookmark)
+				fs.appendAnchor(bm.Literal, fs.fileName, bm.RefText)
+			}
+		case *ast.BibAnchor:
+			if !skipCurChapter {
+				//citations "<<ref>>" are shown as "[label]"
+				ba := b.(*ast.BibAnchor)
+				fs.appendAnchor(ba.Id, fs.fileName, "["+ba.Label+"]")
+			}
+		case *ast.IndexTerm:
+			if !skipCurChapter {
+				fs.appendIndexEntry(b.(*ast.IndexTerm), curHeader)
 			}
 		}
 		return true
@@ -377,7 +470,12 @@ func (fs *FileSplitter)	fillIdMap(printYaml bool) {
 	}
 }
 
+// navMu serializes the updates of the navigation file, several documents could be written into the same mkdocs.yml
+var navMu sync.Mutex
+
 func (fs *FileSplitter) writeNavToFile(navFile string, docFile string, nav []string) error {
+	navMu.Lock()
+	defer navMu.Unlock()
 	data, err := ioutil.ReadFile(navFile)
 	if err != nil {
 		return err
@@ -386,8 +484,7 @@ func (fs *FileSplitter) writeNavToFile(navFile string, docFile string, nav []str
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(navFile, []byte(out), 666)
-	return err
+	return fs.writeFile(navFile, []byte(out))
 }
 
 var permLinkRE = regexp.MustCompile(`[^a-яА-Яa-zA-Z0-9]+`)
@@ -397,36 +494,31 @@ func (fs *FileSplitter) appendIdMap(doc string, id string, file string, caption
 		fs.idMaps[fs.doc.Name] = make(IdMap)
 	}
 	if id != "" {
-		fs.idMaps[fs.doc.Name][id] = &IdMapEntry{file, caption}
+		fs.idMaps[fs.doc.Name][id] = &IdMapEntry{FileName: file, Caption: caption}
 	}
 	if caption != "" {
-		perm := strings.ToLower(caption)
-		perm = permLinkRE.ReplaceAllLiteralString(perm, "-")
-		fs.idMaps[fs.doc.Name][perm] = &IdMapEntry{file, caption}
+		fs.idMaps[fs.doc.Name][permLink(caption)] = &IdMapEntry{FileName: file, Caption: caption}
+	}
+}
+
+// appendAnchor adds an anchor id into the current document's idmap, caption is the anchor's reftext
+func (fs *FileSplitter) appendAnchor(id string, file string, caption string) {
+	if fs.idMaps[fs.doc.Name] == nil {
+		fs.idMaps[fs.doc.Name] = make(IdMap)
+	}
+	if id != "" {
+		fs.idMaps[fs.doc.Name][id] = &IdMapEntry{FileName: file, Caption: caption}
 	}
 }
 
+// permLink returns the header anchor generated by markdown toc extension
+func permLink(caption string) string {
+	return permLinkRE.ReplaceAllLiteralString(strings.ToLower(caption), "-")
+}
+
 func (fs *FileSplitter) findIdMap(doc string, id string) *IdMapEntry {
-	ctx := context.Background()
 	if fs.idMaps[doc] == nil {
-		//try to read *.idmap file
-		data, err := ioutil.ReadFile(filepath.Join(fs.conf.ArtifactsDir, doc) + ".idmap")
-		if err != nil {
-			fs.log.Error(ctx, "cannot load idmap file", slog.F("err", err))
-			//no file, create emtpy map
-			fs.idMaps[doc] = make(IdMap)
-			return nil
-		}
-		var idm IdMap
-		err = yaml.Unmarshal(data, &idm)
-		if err != nil {
-			fs.log.Error(ctx, "cannot load idmap file", slog.F("err", err))
-			//no file, create emtpy map
-			fs.idMaps[doc] = make(IdMap)
-			return nil
-		}
-
-		fs.idMaps[doc] = idm
+		fs.idMaps[doc] = fs.loadIdMap(doc)
 	}
 	if id == "" {
 		//link to the document without id
@@ -436,17 +528,47 @@ func (fs *FileSplitter) findIdMap(doc string, id string) *IdMapEntry {
 	return fs.idMaps[doc][id]
 }
 
+// loadIdMap returns the idmap of the other document, the map is empty if it cannot be loaded
+func (fs *FileSplitter) loadIdMap(doc string) IdMap {
+	ctx := context.Background()
+	f, err := fs.registry.Get(doc)
+	if err != nil {
+		fs.log.Error(ctx, "cannot load idmap", slog.F("doc", doc), slog.Error(err))
+		return make(IdMap)
+	}
+	if stale, err := f.Stale(); err != nil {
+		fs.log.Warn(ctx, "cannot check idmap sources", slog.F("doc", doc), slog.Error(err))
+	} else if stale {
+		fs.log.Warn(ctx, "idmap is out of date, regenerate it", slog.F("doc", doc))
+	}
+	if f.Ids == nil {
+		return make(IdMap)
+	}
+	return f.Ids
+}
+
+// SetIdMaps makes the splitter use the registry shared with the other splitters
+func (fs *FileSplitter) SetIdMaps(r *idmap.Registry) {
+	fs.registry = r
+}
+
+// SetSources sets the document and included files, the hash of the files is stored in the idmap
+func (fs *FileSplitter) SetSources(files []string) {
+	fs.sources = files
+}
+
 func (fs *FileSplitter) init(fillMapOnly bool) error {
 	fs.firstHeader = fs.findFirstHeader()
 	fs.fileName = fs.getNextFileName(fs.firstHeader)
 	fs.fileNames = append(fs.fileNames, fs.fileName)
+	fs.fileHeaders = append(fs.fileHeaders, fileHeader{fs.firstHeader, fs.firstHeaderDoc})
 	fs.fillIdMap(false)
 	if fillMapOnly {
 		if len(fs.idMaps) != 1 {
 			return errors.New("several id maps found")
 		}
 		for _, m := range fs.idMaps {
-			err := fs.writeIdMap(filepath.Join(fs.conf.ArtifactsDir, fs.doc.Name + ".idmap"), m)
+			err := fs.writeIdMap(m)
 			if err != nil {
 				return err
 			}
@@ -454,6 +576,7 @@ func (fs *FileSplitter) init(fillMapOnly bool) error {
 	}
 	if !fillMapOnly {
 		fs.fixUrls()
+		fs.fillToc()
 	}
 	return nil
 }
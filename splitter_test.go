@@ -7,6 +7,7 @@ import (
 	"cdr.dev/slog/sloggers/slogtest"
 	"context"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -72,11 +73,14 @@ func TestSplitter_NextFile(t *testing.T) {
 			return
 		}
 		assert.Equal(t, splitter.fileNames[i], splitter.fileName)
-		assert.NotNil(t, splitter.file)
+		assert.NotNil(t, splitter.buf)
 		assert.NotNil(t, splitter.w)
-		assert.FileExists(t, splitter.fileName)
 	}
 	splitter.Close()
+	for i := range []int{0,1} {
+		//files are written when the next file is started or the splitter is closed
+		assert.FileExists(t, splitter.fileNames[i])
+	}
 	for i := range []int{0,1} {
 		err = os.Remove(splitter.fileNames[i])
 		assert.NoError(t, err)
@@ -106,6 +110,56 @@ func TestSplitter_WriteMarkdown(t *testing.T) {
 	}
 }
 
+func TestSplitter_WriteUnchanged(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	dir, err := ioutil.TempDir("", "splitter")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	render := func(input string) *FileSplitter {
+		doc, err := parser.New(input, nil, logger).Parse("gotest.adoc")
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		splitter := NewFileSplitter(doc, "slug", &settings.Config{ArtifactsDir: dir}, dir, 2, logger)
+		assert.NoError(t, splitter.RenderMarkdown(""))
+		return splitter
+	}
+	if fs := render(splitterTestInput); fs != nil {
+		assert.Equal(t, len(fs.fileNames), fs.updated)
+	}
+	//nothing is changed
+	if fs := render(splitterTestInput); fs != nil {
+		assert.Equal(t, 0, fs.updated)
+	}
+	//only the last file is changed
+	if fs := render(splitterTestInput + "\nnew text\n"); fs != nil {
+		assert.Equal(t, 1, fs.updated)
+		data, err := ioutil.ReadFile(filepath.Join(dir, fs.fileNames[len(fs.fileNames)-1]))
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "new text")
+	}
+}
+
+func TestSplitter_WriteError(t *testing.T) {
+	logger := slogtest.Make(t, &slogtest.Options{IgnoreErrors: true})
+	dir, err := ioutil.TempDir("", "splitter")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	doc, err := parser.New(splitterTestInput, nil, logger).Parse("gotest.adoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	//output folder doesn't exist
+	splitter := NewFileSplitter(doc, "slug", &settings.Config{ArtifactsDir: dir}, filepath.Join(dir, "none"), 2, logger)
+	err = splitter.RenderMarkdown("")
+	assert.Error(t, err)
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestSplitter(t *testing.T) {
 	ctx := context.Background()
 	conf := testConf()
@@ -0,0 +1,156 @@
+// Package subs implements asciidoc text substitutions which are not covered by markdown:
+// replacements of special character sequences and character references.
+package subs
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// substitution groups, see https://docs.asciidoctor.org/asciidoc/latest/subs/#substitution-groups
+var groups = map[string][]string{
+	"none":     {},
+	"normal":   {"specialcharacters", "quotes", "attributes", "replacements", "macros", "post_replacements"},
+	"verbatim": {"specialcharacters", "callouts"},
+}
+
+// short names of substitutions
+var aliases = map[string]string{
+	"specialchars": "specialcharacters",
+	"a":            "attributes",
+	"m":            "macros",
+	"n":            "normal",
+	"p":            "post_replacements",
+	"q":            "quotes",
+	"r":            "replacements",
+	"c":            "specialcharacters",
+	"v":            "verbatim",
+}
+
+// Enabled checks if the substitution is enabled by the block "subs" attribute value, e.g. "-replacements",
+// "+quotes", "macros+" or "verbatim,quotes". Empty value means normal substitutions.
+func Enabled(value string, name string) bool {
+	set := make(map[string]bool)
+	incremental := true
+	var entries []string
+	for _, e := range strings.Split(value, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			entries = append(entries, e)
+			if !strings.HasPrefix(e, "+") && !strings.HasPrefix(e, "-") && !strings.HasSuffix(e, "+") {
+				incremental = false
+			}
+		}
+	}
+	if incremental {
+		add(set, "normal", true)
+	}
+	for _, e := range entries {
+		switch {
+		case strings.HasPrefix(e, "-"):
+			add(set, e[1:], false)
+		case strings.HasPrefix(e, "+"):
+			add(set, e[1:], true)
+		case strings.HasSuffix(e, "+"):
+			add(set, e[:len(e)-1], true)
+		default:
+			add(set, e, true)
+		}
+	}
+	return set[name]
+}
+
+func add(set map[string]bool, name string, on bool) {
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	if g, ok := groups[name]; ok {
+		for _, n := range g {
+			set[n] = on
+		}
+		return
+	}
+	set[name] = on
+}
+
+type replacement struct {
+	re *regexp.Regexp
+	// replaced character sequence
+	seq string
+	// text which replaces the sequence
+	text string
+	// the match should be followed by a word character
+	followed bool
+}
+
+// Asciidoctor replacements, see https://docs.asciidoctor.org/asciidoc/latest/subs/replacements/.
+// Optional backslash escapes the replacement.
+var replacements = []replacement{
+	{re: regexp.MustCompile(`\\?\(C\)`), seq: "(C)", text: "©"},
+	{re: regexp.MustCompile(`\\?\(R\)`), seq: "(R)", text: "®"},
+	{re: regexp.MustCompile(`\\?\(TM\)`), seq: "(TM)", text: "™"},
+	// em dash surrounded by spaces
+	{re: regexp.MustCompile(`(?:^| |\\)--(?: |$)`), seq: "--", text: "—"},
+	// em dash between words
+	{re: regexp.MustCompile(`[\pL\pN_]\\?--`), seq: "--", text: "—", followed: true},
+	{re: regexp.MustCompile(`\\?\.\.\.`), seq: "...", text: "…"},
+	// apostrophe between letters
+	{re: regexp.MustCompile(`[\pL\pN]\\?'`), seq: "'", text: "’", followed: true},
+	{re: regexp.MustCompile(`\\?->`), seq: "->", text: "→"},
+	{re: regexp.MustCompile(`\\?=>`), seq: "=>", text: "⇒"},
+	{re: regexp.MustCompile(`\\?<-`), seq: "<-", text: "←"},
+	{re: regexp.MustCompile(`\\?<=`), seq: "<=", text: "⇐"},
+}
+
+// "&#169;", "&#xA9;" or "&copy;"
+var charRefRE = regexp.MustCompile(`&(?:[a-zA-Z][a-zA-Z]+\d{0,2}|#\d\d\d{0,4}|#x[\da-fA-F][\da-fA-F][\da-fA-F]{0,3});`)
+
+// "+++passthrough text+++"
+var passThruRE = regexp.MustCompile(`\+{3}.+?\+{3}`)
+
+// Replacements applies asciidoc replacements substitution: "(C)" becomes "©", "--" becomes "—", "=>" becomes "⇒"
+// and so on, character references are decoded. Passthrough text "+++text+++" is left unchanged.
+func Replacements(s string) string {
+	var b strings.Builder
+	beg := 0
+	for _, ind := range passThruRE.FindAllStringIndex(s, -1) {
+		b.WriteString(replace(s[beg:ind[0]]))
+		b.WriteString(s[ind[0]:ind[1]])
+		beg = ind[1]
+	}
+	b.WriteString(replace(s[beg:]))
+	return b.String()
+}
+
+func replace(s string) string {
+	for _, r := range replacements {
+		s = r.apply(s)
+	}
+	return charRefRE.ReplaceAllStringFunc(s, html.UnescapeString)
+}
+
+func (r *replacement) apply(s string) string {
+	var b strings.Builder
+	beg := 0
+	for _, ind := range r.re.FindAllStringIndex(s, -1) {
+		if r.followed {
+			next, _ := utf8.DecodeRuneInString(s[ind[1]:])
+			if !(unicode.IsLetter(next) || unicode.IsDigit(next) || next == '_') {
+				continue
+			}
+		}
+		match := s[ind[0]:ind[1]]
+		b.WriteString(s[beg:ind[0]])
+		if strings.Contains(match, `\`+r.seq) {
+			//escaped sequence, remove the backslash only
+			b.WriteString(strings.Replace(match, `\`+r.seq, r.seq, 1))
+		} else {
+			b.WriteString(strings.Replace(match, r.seq, r.text, 1))
+		}
+		beg = ind[1]
+	}
+	b.WriteString(s[beg:])
+	return b.String()
+}
@@ -0,0 +1,37 @@
+package subs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReplacements(t *testing.T) {
+	cases := []struct {
+		input string
+		exp   string
+	}{
+		{"(C) Company (R) Product(TM)", "© Company ® Product™"},
+		{"text -- more", "text — more"},
+		{"слово--слово and end--", "слово—слово and end--"},
+		{"wait...", "wait…"},
+		{"don't", "don’t"},
+		{"a -> b => c <- d <= e", "a → b ⇒ c ← d ⇐ e"},
+		{`Field1\=>Field2 \(C) \--`, `Field1=>Field2 (C) --`},
+		{"&#8212; &#x2014; &copy;", "— — ©"},
+		{"a +++(C) ->+++ b (C)", "a +++(C) ->+++ b ©"},
+		{"--config option", "--config option"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.exp, Replacements(c.input), c.input)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	assert.True(t, Enabled("", "replacements"))
+	assert.False(t, Enabled("-replacements", "replacements"))
+	assert.False(t, Enabled("verbatim", "replacements"))
+	assert.False(t, Enabled("none", "replacements"))
+	assert.True(t, Enabled("verbatim,+replacements", "replacements"))
+	assert.True(t, Enabled("quotes,r", "replacements"))
+	assert.False(t, Enabled("normal,-r", "replacements"))
+}
@@ -8,11 +8,14 @@ type Token struct {
 	Type TokenType
 	Literal string
 	Line uint
+	Col uint //column of the first char, in runes starting from 1
+	EndLine uint //line of the last char
+	EndCol uint //column of the last char
 	//GetState int
 }
 
 func (t *Token) String() string {
-	return fmt.Sprintf("[ type:%v, line:%v, literal:%s ]", t.Type, t.Line, t.Literal)
+	return fmt.Sprintf("[ type:%v, line:%v, col:%v, literal:%s ]", t.Type, t.Line, t.Col, t.Literal)
 }
 
 const (
@@ -53,6 +56,20 @@ const (
 	INCLUDE  //include directive "include::RoutingGuide.adoc[leveloffset=+1]"
 	COMMENT
 	SIDEBAR //sidebar block delimiter "\n****"
+	CHECKBOX //checklist item state "[x]", "[*]" or "[ ]" right after the list marker
+	ATTR_ENTRY //document attribute entry ":name: value"
+	INDEX_TERM //"((term))", "(((primary, secondary)))", "indexterm:[...]" or "indexterm2:[...]"
+	KBD //keyboard macro "kbd:[Ctrl+S]"
+	BTN //button macro "btn:[Save]"
+	MENU //menu macro "menu:File[Save As]"
+	COMMENT_BLOCK //"////" delimited comment block
+	TOC //table of contents macro "toc::[]"
+	BIB_ANCHOR //bibliography entry anchor "[[[ref]]]" or "[[[ref,label]]]"
+	PASS_DELIM //passthrough block delimiter "++++"
+	PASS_BLOCK //passthrough block content, stem blocks are passthrough blocks too
+	STEM //inline stem macro "stem:[...]", "latexmath:[...]" or "asciimath:[...]"
+	BLOCK_VIDEO //video block macro "video::file.mp4[]"
+	BLOCK_AUDIO //audio block macro "audio::file.mp3[]"
 )
 
 var names = map[TokenType]string{
@@ -93,6 +110,20 @@ COMMENT:      "COMMENT",
 SIDEBAR:      "SIDEBAR", //sidebar block delimiter "\n****"
 L_BOUNDARY:   "L_BOUNDARY",
 CALLOUT_MARK: "CALLOUT_MARK",
+CHECKBOX:     "CHECKBOX", //checklist item state "[x]", "[*]" or "[ ]"
+ATTR_ENTRY:   "ATTR_ENTRY", //document attribute entry ":name: value"
+INDEX_TERM:   "INDEX_TERM", //index term
+KBD:          "KBD", //keyboard macro
+BTN:          "BTN", //button macro
+MENU:         "MENU", //menu macro
+COMMENT_BLOCK: "COMMENT_BLOCK", //comment block
+TOC:          "TOC", //table of contents macro
+BIB_ANCHOR:   "BIB_ANCHOR", //bibliography anchor
+PASS_DELIM:   "PASS_DELIM", //passthrough block delimiter
+PASS_BLOCK:   "PASS_BLOCK", //passthrough block
+STEM:         "STEM", //inline stem macro
+BLOCK_VIDEO:  "BLOCK_VIDEO", //video block macro
+BLOCK_AUDIO:  "BLOCK_AUDIO", //audio block macro
 }
 
 // Stringer implementation
@@ -103,3 +134,13 @@ func (t TokenType) String() string {
 	}
 	return fmt.Sprintf("(name not found: %v)", int(t))
 }
+
+// Lookup returns the token type by its name, ILLEGAL is returned for unknown names
+func Lookup(name string) TokenType {
+	for t, n := range names {
+		if n == name {
+			return t
+		}
+	}
+	return ILLEGAL
+}
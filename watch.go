@@ -0,0 +1,122 @@
+package main
+
+import (
+	"asciidoc2md/diag"
+	"asciidoc2md/html"
+	"asciidoc2md/watch"
+	"cdr.dev/slog"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// watchDelay collects the changes made by an editor in a single rebuild
+const watchDelay = 200 * time.Millisecond
+
+func watchFile() {
+	ctx := context.Background()
+	//the files are polled without --poll too if inotify is not available
+	if cli.Watch.Interval <= 0 {
+		log.Fatal(ctx, "invalid option", slog.Error(fmt.Errorf("polling interval must be positive: %v", cli.Watch.Interval)))
+	}
+	w := watch.New(cli.Watch.Poll, cli.Watch.Interval, log)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	go func() {
+		<-stop
+		w.Close()
+	}()
+	deps := rebuild()
+	for {
+		if err := w.Watch(deps); err != nil {
+			log.Error(ctx, "cannot watch files", slog.Error(err))
+		}
+		log.Info(ctx, "watching files", slog.F("count", len(deps)))
+		changed, ok := watch.Collect(w, watchDelay)
+		if !ok {
+			return
+		}
+		log.Info(ctx, "files changed", slog.F("files", changed))
+		deps = rebuild()
+	}
+}
+
+// rebuild regenerates the idmap and converts the document, only the changed output files are written.
+// It returns the files the document depends on: the document itself, included files, the config and
+// the idmaps of the linked documents.
+func rebuild() (deps []string) {
+	ctx := context.Background()
+	start := time.Now()
+	deps = []string{cli.Watch.Input}
+	if cli.Config != "" {
+		deps = append(deps, cli.Config)
+	}
+	//keep watching if the document is broken
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(ctx, "cannot convert document", slog.F("err", fmt.Sprint(r)))
+		}
+	}()
+	conf, err := loadConfigCLI(cli.Config, &cli)
+	if err != nil {
+		log.Error(ctx, "cannot load config", slog.Error(err))
+		return deps
+	}
+	var diags diag.Collector
+	doc, includes, err := parseFileDeps(cli.Watch.Input, log, &diags)
+	deps = append(deps, includes...)
+	if err != nil {
+		log.Error(ctx, "cannot parse document", slog.Error(err))
+		return deps
+	}
+	diags.Print(os.Stderr)
+	if n := diags.Count(diag.Error); n > conf.MaxErrors {
+		log.Error(ctx, "too many parse errors", slog.F("errors", n), slog.F("max", conf.MaxErrors))
+		return deps
+	}
+
+	//idmaps are loaded again on every rebuild, they could be regenerated by the other watchers
+	idMaps := newIdMapRegistry(conf.ArtifactsDir)
+	gen := NewFileSplitter(doc, cli.Slug, conf, "", cli.SplitLevel, log)
+	gen.SetIdMaps(idMaps)
+	gen.SetSources(append([]string{cli.Watch.Input}, includes...))
+	if err = gen.GenerateIdMap(); err != nil {
+		log.Error(ctx, "cannot generate idmap", slog.Error(err))
+		return deps
+	}
+	splitter := NewFileSplitter(doc, cli.Slug, conf, cli.Watch.Out, cli.SplitLevel, log)
+	splitter.SetIdMaps(idMaps)
+	if cli.Watch.Format == "html" {
+		//a broken template is watched too, so fixing it rebuilds the document
+		if cli.Watch.Template != "" {
+			deps = append(deps, cli.Watch.Template)
+		}
+		layout, err := html.LoadLayout(cli.Watch.Template)
+		if err != nil {
+			log.Error(ctx, "cannot load layout", slog.Error(err))
+			return deps
+		}
+		err = splitter.RenderHtml(cli.Watch.ImagePath, layout, cli.Watch.Single)
+	} else {
+		err = splitter.RenderMarkdown(cli.Watch.ImagePath)
+	}
+	//the idmaps of the linked documents are loaded while rendering,
+	//a single json storage is watched too, its unchanged content isn't rewritten by the next rebuild
+	seen := make(map[string]bool)
+	for name := range splitter.idMaps {
+		path := idMaps.Storage().Path(name)
+		if name != doc.Name && path != "" && !seen[path] {
+			seen[path] = true
+			deps = append(deps, path)
+		}
+	}
+	if err != nil {
+		log.Error(ctx, "cannot convert document", slog.Error(err))
+		return deps
+	}
+	log.Info(ctx, "document converted", slog.F("files", len(splitter.fileNames)),
+		slog.F("updated", gen.updated+splitter.updated), slog.F("time", time.Since(start)))
+	return deps
+}
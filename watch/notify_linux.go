@@ -0,0 +1,130 @@
+//go:build linux
+// +build linux
+
+package watch
+
+import (
+	"cdr.dev/slog"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// the directories are watched instead of the files, editors often replace the file by renaming a new one
+const notifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// Notify is the inotify watcher
+type Notify struct {
+	log     slog.Logger
+	fd      int
+	file    *os.File //inotify descriptor, closing the file stops reading
+	mu      sync.Mutex
+	dirs    map[int]string //watch descriptor -> directory
+	wds     map[string]int //directory -> watch descriptor
+	files   map[string]bool
+	changes chan string
+	done    chan struct{}
+	once    sync.Once
+}
+
+func NewNotify(log slog.Logger) (Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	n := &Notify{
+		log:     log,
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		dirs:    make(map[int]string),
+		wds:     make(map[string]int),
+		files:   make(map[string]bool),
+		changes: make(chan string, 16),
+		done:    make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+func (n *Notify) Watch(files []string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.files = make(map[string]bool, len(files))
+	dirs := make(map[string]bool)
+	for _, name := range files {
+		name = filepath.Clean(name)
+		n.files[name] = true
+		dirs[filepath.Dir(name)] = true
+	}
+	for dir := range dirs {
+		if _, ok := n.wds[dir]; ok {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, dir, notifyMask)
+		if err != nil {
+			return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+		}
+		n.wds[dir] = wd
+		n.dirs[wd] = dir
+	}
+	for dir, wd := range n.wds {
+		if !dirs[dir] {
+			syscall.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.wds, dir)
+			delete(n.dirs, wd)
+		}
+	}
+	return nil
+}
+
+func (n *Notify) Changes() <-chan string {
+	return n.changes
+}
+
+func (n *Notify) Close() error {
+	var err error
+	n.once.Do(func() {
+		close(n.done)
+		err = n.file.Close()
+	})
+	return err
+}
+
+func (n *Notify) run() {
+	defer close(n.changes)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			select {
+			case <-n.done:
+			default:
+				n.log.Error(context.Background(), "cannot read inotify events", slog.Error(err))
+			}
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= size; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + syscall.SizeofInotifyEvent
+			off = start + int(ev.Len)
+			name := strings.TrimRight(string(buf[start:off]), "\x00")
+			n.mu.Lock()
+			file := filepath.Join(n.dirs[int(ev.Wd)], name)
+			watched := n.files[file]
+			n.mu.Unlock()
+			if !watched {
+				continue
+			}
+			n.log.Debug(context.Background(), "file changed", slog.F("file", file))
+			select {
+			case n.changes <- file:
+			case <-n.done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package watch
+
+import (
+	"cdr.dev/slog"
+	"errors"
+)
+
+// NewNotify fails on the platforms without inotify, use the polling watcher
+func NewNotify(log slog.Logger) (Watcher, error) {
+	return nil, errors.New("inotify is not supported on this platform")
+}
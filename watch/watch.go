@@ -0,0 +1,163 @@
+// Package watch reports changes of the files. Watcher is implemented with inotify on linux, polling is available
+// everywhere as a fallback.
+package watch
+
+import (
+	"cdr.dev/slog"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Watcher sends the names of the changed files to the Changes channel
+type Watcher interface {
+	// Watch replaces the set of the watched files, the files may not exist yet
+	Watch(files []string) error
+	Changes() <-chan string
+	Close() error
+}
+
+// New creates inotify watcher, the polling one is returned if inotify is not available or poll is set
+func New(poll bool, interval time.Duration, log slog.Logger) Watcher {
+	if !poll {
+		w, err := NewNotify(log)
+		if err == nil {
+			return w
+		}
+		log.Warn(context.Background(), "cannot use inotify, polling files", slog.Error(err))
+	}
+	return NewPoller(interval, log)
+}
+
+// Collect waits for the first change and then for the next changes during the delay, so several writes
+// of an editor give a single rebuild. The names are cleaned and unique.
+func Collect(w Watcher, delay time.Duration) ([]string, bool) {
+	name, ok := <-w.Changes()
+	if !ok {
+		return nil, false
+	}
+	seen := map[string]bool{filepath.Clean(name): true}
+	names := []string{filepath.Clean(name)}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case name, ok := <-w.Changes():
+			if !ok {
+				return names, true
+			}
+			name = filepath.Clean(name)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		case <-timer.C:
+			return names, true
+		}
+	}
+}
+
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func stat(name string) fileState {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{true, fi.Size(), fi.ModTime()}
+}
+
+func (s fileState) same(o fileState) bool {
+	return s.exists == o.exists && s.size == o.size && s.modTime.Equal(o.modTime)
+}
+
+// Poller checks the size and the modification time of the files every interval
+type Poller struct {
+	interval time.Duration
+	log      slog.Logger
+	mu       sync.Mutex
+	files    map[string]fileState
+	changes  chan string
+	done     chan struct{}
+	once     sync.Once
+}
+
+func NewPoller(interval time.Duration, log slog.Logger) *Poller {
+	p := &Poller{
+		interval: interval,
+		log:      log,
+		files:    make(map[string]fileState),
+		changes:  make(chan string, 16),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *Poller) Watch(files []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	states := make(map[string]fileState, len(files))
+	for _, name := range files {
+		name = filepath.Clean(name)
+		//the known files keep their state, so the changes made before Watch call are not lost
+		if state, ok := p.files[name]; ok {
+			states[name] = state
+		} else {
+			states[name] = stat(name)
+		}
+	}
+	p.files = states
+	return nil
+}
+
+func (p *Poller) Changes() <-chan string {
+	return p.changes
+}
+
+func (p *Poller) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *Poller) run() {
+	defer close(p.changes)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		for _, name := range p.check() {
+			p.log.Debug(context.Background(), "file changed", slog.F("file", name))
+			select {
+			case p.changes <- name:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// check returns the changed files and remembers their new state
+func (p *Poller) check() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var changed []string
+	for name, old := range p.files {
+		cur := stat(name)
+		if !cur.same(old) {
+			p.files[name] = cur
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,54 @@
+package watch
+
+import (
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchers(t *testing.T) {
+	logger := slogtest.Make(t, nil).Leveled(slog.LevelDebug)
+	notify, err := NewNotify(logger)
+	if err != nil {
+		t.Log(err)
+	}
+	cases := []struct {
+		name string
+		w    Watcher
+	}{
+		{"poller", NewPoller(10*time.Millisecond, logger)},
+		{"inotify", notify},
+	}
+	for _, c := range cases {
+		if c.w == nil {
+			continue
+		}
+		dir, err := ioutil.TempDir("", "watch")
+		if !assert.NoError(t, err, c.name) {
+			continue
+		}
+		watched := filepath.Join(dir, "doc.adoc")
+		other := filepath.Join(dir, "other.adoc")
+		assert.NoError(t, ioutil.WriteFile(watched, []byte("one"), 0666), c.name)
+		//not existing files are watched too
+		created := filepath.Join(dir, "new.adoc")
+		assert.NoError(t, c.w.Watch([]string{watched, created}), c.name)
+
+		assert.NoError(t, ioutil.WriteFile(other, []byte("other"), 0666), c.name)
+		assert.NoError(t, ioutil.WriteFile(watched, []byte("changed"), 0666), c.name)
+		assert.NoError(t, ioutil.WriteFile(created, []byte("new"), 0666), c.name)
+		names, ok := Collect(c.w, 200*time.Millisecond)
+		assert.True(t, ok, c.name)
+		assert.ElementsMatch(t, []string{watched, created}, names, c.name)
+
+		assert.NoError(t, c.w.Close(), c.name)
+		_, ok = Collect(c.w, time.Millisecond)
+		assert.False(t, ok, c.name)
+		os.RemoveAll(dir)
+	}
+}